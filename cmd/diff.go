@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Intuit Inc.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/config"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/manager"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/spf13/cobra"
+)
+
+const (
+	unifiedOutput = "unified"
+
+	// exit codes of diff command, following the convention of diff(1)
+	driftExitCode = 1
+	errorExitCode = 2
+)
+
+var diffOutput string
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare desired shards with live shards",
+	Long: `Compare the shards sharding manager would produce right now with the live shards in the shard namespace.
+Exits with code 1 when shards drifted and with code 2 when the comparison could not be made.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := config.Validate(&smParams)
+		if err != nil {
+			log.Printf("invalid configuration: %v", err)
+			os.Exit(errorExitCode)
+		}
+		diffs, err := diffShards()
+		if err != nil {
+			log.Printf("failed to compare shards: %v", err)
+			os.Exit(errorExitCode)
+		}
+		err = printShardDiffs(cmd.OutOrStdout(), diffs, diffOutput)
+		if err != nil {
+			log.Printf("failed to print shard differences: %v", err)
+			os.Exit(errorExitCode)
+		}
+		if len(diffs) > 0 {
+			os.Exit(driftExitCode)
+		}
+	},
+}
+
+func init() {
+	addKubeClientFlags(diffCmd.Flags(), &smParams)
+	addShardingManagerFlags(diffCmd.Flags(), &smParams)
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", unifiedOutput, "Output format, one of \"unified\" or \"json\"")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+func diffShards() ([]model.ShardDiff, error) {
+	clients, err := loadClients()
+	if err != nil {
+		return nil, err
+	}
+	// desired shards are derived in dry run mode so that no event, handoff or revision is recorded
+	params := smParams
+	params.DryRun = true
+	shardingManager, err := manager.NewShardingManager(ctx,
+		controller.NewShardHandler(clients, &params),
+		controller.NewOperatorHandler(clients, &params),
+		controller.NewOverrideHandler(clients, &params),
+		controller.NewRevisionHandler(clients, &params),
+		clients,
+		&params)
+	if err != nil {
+		return nil, err
+	}
+	desired, live, err := shardingManager.DesiredShards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return manager.DiffShards(desired, live, smParams.OperatorIdentityLabel), nil
+}
+
+func printShardDiffs(out io.Writer, diffs []model.ShardDiff, output string) error {
+	switch output {
+	case jsonOutput:
+		if diffs == nil {
+			diffs = []model.ShardDiff{}
+		}
+		data, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case unifiedOutput:
+		for _, diff := range diffs {
+			fmt.Fprintf(out, "--- live/%s\n+++ desired/%s\n@@ operator %s @@\n", diff.Shard, diff.Shard, diff.Operator)
+			for _, cluster := range diff.RemovedClusters {
+				fmt.Fprintf(out, "-cluster %s\n", cluster)
+			}
+			for _, cluster := range diff.AddedClusters {
+				fmt.Fprintf(out, "+cluster %s\n", cluster)
+			}
+			for _, identity := range diff.RemovedIdentities {
+				fmt.Fprintf(out, "-identity %s\n", identity)
+			}
+			for _, identity := range diff.AddedIdentities {
+				fmt.Fprintf(out, "+identity %s\n", identity)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+}
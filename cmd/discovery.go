@@ -5,27 +5,38 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/config"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/server"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	ctx = context.Background()
 )
 
+// interval at which the configuration file is checked for changes
+const configWatchInterval = 10 * time.Second
+
 // discoveryCmd represents the discovery command
 var discoveryCmd = &cobra.Command{
 	Use:   "discovery",
 	Short: "Discover configuration to distribute amongst admiral operators",
 	Long:  `Discover configuration to distribute amongst admiral operators.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		err := config.Validate(&smParams)
+		if err != nil {
+			log.Fatalf("invalid configuration: %v", err)
+		}
 		//initialize monitoring and start servers
 		wg := new(sync.WaitGroup)
 		wg.Add(1)
@@ -44,17 +55,93 @@ var discoveryCmd = &cobra.Command{
 }
 
 func init() {
-	discoveryCmd.PersistentFlags().StringVar(&smParams.KubeconfigPath, "kube_config", "", "Use a Kubernetes configuration file instead of in-cluster configuration")
+	addKubeClientFlags(discoveryCmd.PersistentFlags(), &smParams)
+	addShardingManagerFlags(discoveryCmd.Flags(), &smParams)
+	//shards are rendered as yaml manifests to this directory instead of being created through the admiral api
+	discoveryCmd.Flags().StringVar(&smParams.OutputDir, "output-dir", "", "Directory to render shards to as YAML manifests instead of creating them through the Admiral API")
+	discoveryCmd.Flags().StringVar(&smParams.OperatorsFile, "operators-file", "", "YAML or JSON file defining operators with their capacity, locality, segment, pool and scheduling state, required with output-dir as the cluster is not accessed")
+	//the full pipeline runs but shard changes are only logged and reported through metrics and the admin api
+	discoveryCmd.Flags().BoolVar(&smParams.DryRun, "dry-run", false, "Report the shard creations, updates and deletions which would be made instead of making them")
+	//admin endpoints rolling back the assignment, resuming rebalancing or accepting registry changes require this token
+	discoveryCmd.Flags().StringVar(&smParams.AdminTokenFile, "admin-token-file", "", "File holding the bearer token required by the admin endpoints which change the assignment, read on every request so that it can be rotated. These endpoints are disabled when not set")
+
+	rootCmd.AddCommand(discoveryCmd)
+}
+
+// binds flags which define how kubernetes clients connect to the cluster sharding manager runs against
+func addKubeClientFlags(flags *pflag.FlagSet, params *model.ShardingManagerParams) {
+	flags.StringVar(&params.KubeconfigPath, "kube_config", "", "Use a Kubernetes configuration file instead of in-cluster configuration")
+	//context of the kubeconfig file, current context is used when empty
+	flags.StringVar(&params.KubeconfigContext, "kube-context", "", "Context of the Kubernetes configuration file to use instead of the current context")
+	//requests are sent on behalf of the impersonated user and groups
+	flags.StringVar(&params.ImpersonateUser, "as", "", "User to impersonate for requests to the Kubernetes API")
+	flags.StringSliceVar(&params.ImpersonateGroups, "as-group", nil, "Groups to impersonate for requests to the Kubernetes API, can be repeated")
+	//client side rate limits, client-go defaults are used when not set
+	flags.Float32Var(&params.KubeClientQPS, "kube-qps", 0, "Maximum queries per second to the Kubernetes API, 0 uses the client default")
+	flags.IntVar(&params.KubeClientBurst, "kube-burst", 0, "Maximum burst of queries to the Kubernetes API, 0 uses the client default")
+}
+
+// binds flags which define how configuration is distributed amongst admiral operators
+func addShardingManagerFlags(flags *pflag.FlagSet, params *model.ShardingManagerParams) {
 	//defines the identity of sharding manager instance - logical name for group of resources handled by an instance of sharding manager. This is used to initialize configuration from registry and as "admiral.io/shardingMangerIdentity" label value on shard crd
-	discoveryCmd.Flags().StringVar(&smParams.ShardingManagerIdentity, "shard-identity", "dev", "Identity of the sharding manager instance, used to get configuration from registry and used as value for label \"admiral.io/shardingMangerIdentity\" on shard crd ")
+	flags.StringVar(&params.ShardingManagerIdentity, "shard-identity", "dev", "Identity of the sharding manager instance, used to get configuration from registry and used as value for label \"admiral.io/shardingMangerIdentity\" on shard crd ")
 	//operator identity label which will be set on the shard crd. Using this label value operator will filter the shard it needs to monitor
-	discoveryCmd.Flags().StringVar(&smParams.OperatorIdentityLabel, "operator-identity-label", "admiral.io/operatorIdentity", "label used to specify identity of operator for which shard profile is defined")
+	flags.StringVar(&params.OperatorIdentityLabel, "operator-identity-label", "admiral.io/operatorIdentity", "label used to specify identity of operator for which shard profile is defined")
 	//shard namespace defines the namspace in which sharding manager should drop in shard crds
-	discoveryCmd.Flags().StringVar(&smParams.ShardNamespace, "shard-namespace", "shard-namespace", "Namespace used to create sharding resources")
+	flags.StringVar(&params.ShardNamespace, "shard-namespace", "shard-namespace", "Namespace used to create sharding resources")
 	//registry endpoint
-	discoveryCmd.Flags().StringVar(&smParams.RegistryEndpoint, "registry-endpoint", "", "Registry Service endpoint to get configuration for sharding manager")
-
-	rootCmd.AddCommand(discoveryCmd)
+	flags.StringVar(&params.RegistryEndpoint, "registry-endpoint", "", "Registry Service endpoint to get configuration for sharding manager")
+	//operators which miss their heartbeat for longer than the grace period have their clusters reassigned to healthy operators
+	flags.DurationVar(&params.OperatorGracePeriod, "operator-grace-period", 30*time.Second, "Time an operator can miss its heartbeat before its clusters are reassigned to healthy operators")
+	//strategy used to distribute clusters amongst operators
+	flags.StringVar(&params.DistributionStrategy, "strategy", model.LeastLoadedStrategy, "Strategy used to distribute clusters amongst operators, one of \"least-loaded\", \"locality-aware\", \"segmented\" or \"dependency-aware\"")
+	//operators declare the locality they run in using this label on their heartbeat lease
+	flags.StringVar(&params.OperatorLocalityLabel, "operator-locality-label", "admiral.io/locality", "Label used by operators to declare the locality they run in")
+	//clusters are partitioned by this metadata key and each partition is handled by operators labelled with the same segment
+	flags.StringVar(&params.SegmentKey, "segment-key", "segment", "Cluster metadata key partitioning clusters into segments with strategy \"segmented\"")
+	flags.StringVar(&params.OperatorSegmentLabel, "operator-segment-label", "admiral.io/segment", "Label used by operators to declare the segment whose clusters they handle")
+	//identities of every environment are handled by the operators labelled with the environment's pool
+	flags.StringToStringVar(&params.EnvironmentPools, "environment-pools", nil, "Operator pool handling identities of each environment, e.g. prd=prod,qal=non-prod,e2e=non-prod. Identities of other environments are handled by operators without a pool")
+	flags.StringVar(&params.OperatorPoolLabel, "operator-pool-label", "admiral.io/environmentPool", "Label used by operators to declare the environment pool whose identities they handle")
+	//clusters hosting many identities can be split so that their identities are handled by different operators
+	flags.StringVar(&params.ShardingGranularity, "sharding-granularity", model.ClusterShardingGranularity, "Unit of distribution, one of \"cluster\" or \"identity\"")
+	//clusters moved between operators are kept by their previous operator until the new operator acknowledged them
+	flags.DurationVar(&params.HandoffTimeout, "handoff-timeout", 2*time.Minute, "Time a moved cluster is kept by its previous operator until its new operator acknowledged it, 0 moves clusters at once")
+	//registry configuration removing more clusters than allowed is rejected until accepted through the admin api
+	flags.Float64Var(&params.MaxRegistryRemovalPercent, "max-registry-removal-percent", 50, "Maximum percentage of clusters a registry configuration can remove before it is rejected, 0 means no limit")
+	flags.IntVar(&params.MaxRegistryRemovals, "max-registry-removals", 0, "Maximum number of clusters a registry configuration can remove before it is rejected, 0 means no limit")
+	//clusters are only moved between operators to correct an imbalance beyond the threshold
+	flags.Float64Var(&params.RebalanceThreshold, "rebalance-threshold", 0, "Difference between the highest and lowest operator load, relative to the average load, above which clusters are moved between operators, 0 keeps the current assignment. With the dependency-aware strategy clusters are also moved to reduce cross operator dependencies as long as the load difference stays below it")
+	flags.Float64Var(&params.RebalanceHysteresis, "rebalance-hysteresis", 0, "Amount below rebalance-threshold the load difference is brought back to once clusters are moved")
+	//optional cluster moves are only applied during rebalance windows
+	flags.StringVar(&params.RebalanceWindows, "rebalance-windows", "", "Semicolon separated rebalance windows made of five cron fields evaluated in UTC and a duration, e.g. \"0 2 * * 1-5 3h\", outside of which only mandatory changes are applied and optional moves are queued")
+	//clusters moved between operators which can still handle them are moved in waves
+	flags.IntVar(&params.RolloutMaxMoves, "rollout-max-moves", 0, "Maximum number of clusters moved between healthy operators every rollout interval, remaining moves are queued for later waves, 0 means no limit")
+	flags.DurationVar(&params.RolloutInterval, "rollout-interval", time.Minute, "Interval of rollout waves when rollout-max-moves is set")
+	//operators declare their capacity weight using this annotation or label on their heartbeat lease
+	flags.StringVar(&params.OperatorCapacityKey, "operator-capacity-key", "admiral.io/operatorCapacity", "Annotation or label used by operators to declare their relative capacity weight, a changed capacity only affects where new clusters are placed unless rebalance-threshold is set")
+	//shard size limits, an operator's assignment is split into multiple shards when any of the limits is exceeded
+	flags.IntVar(&params.MaxClustersPerShard, "max-clusters-per-shard", 0, "Maximum number of clusters in a single shard, 0 means no limit")
+	flags.IntVar(&params.MaxIdentitiesPerShard, "max-identities-per-shard", 0, "Maximum number of identities in a single shard, 0 means no limit")
+	flags.IntVar(&params.MaxShardSizeBytes, "max-shard-size-bytes", 1000000, "Maximum serialized size of a single shard in bytes, 0 means no limit")
+	//configmap in shard namespace which pins clusters or identities to operators or excludes them from sharding
+	flags.StringVar(&params.OverridesConfigMap, "overrides-configmap", "admiral-sharding-overrides", "ConfigMap in the shard namespace holding placement overrides")
+	//configmap in shard namespace which keeps the last revisions of the assignment for rollbacks
+	flags.StringVar(&params.RevisionHistoryConfigMap, "revision-history-configmap", "admiral-sharding-revisions", "ConfigMap in the shard namespace keeping the last revisions of the assignment")
+	flags.IntVar(&params.RevisionHistoryLimit, "revision-history-limit", 10, "Number of assignment revisions kept for rollbacks, 0 means no history is kept")
+	//number of clusters moved away from a draining operator on every sync
+	flags.IntVar(&params.DrainBatchSize, "drain-batch-size", 5, "Maximum number of clusters moved away from a draining operator on every sync, 0 means no limit")
+	//operators running in other clusters have their shards published there
+	flags.StringToStringVar(&params.ShardTargets, "shard-targets", nil, "Additional clusters to publish shards to as target name and kubeconfig path pairs, e.g. us-east=/etc/kubeconfig/us-east. Operators running in these clusters must write their heartbeat lease to the local cluster")
+	flags.StringVar(&params.ShardTargetSecretSelector, "shard-target-secret-selector", "", "Label selector of secrets in the shard namespace holding kubeconfigs of additional clusters to publish shards to, keyed by target name")
+	flags.StringVar(&params.OperatorTargetLabel, "operator-target-label", "admiral.io/shardTarget", "Label used by operators to declare the shard target they run in on their heartbeat lease, shards of operators without it are published locally. Leases are only discovered in the shard namespace of the local cluster")
+	//every change of the operator handling a cluster is appended to the audit log as a json line
+	flags.StringVar(&params.AuditLogPath, "audit-log", "", "File to append assignment changes to as JSON lines, \"-\" writes to standard output")
+	flags.IntVar(&params.AuditLogSize, "audit-log-size", 1000, "Number of assignment changes kept in memory for the admin API")
+	//interval between two bulk syncs of registry configuration
+	flags.DurationVar(&params.SyncPeriod, "sync-period", 10*time.Second, "Interval between two bulk syncs of registry configuration")
+	//defines what happens to failed over clusters once the original operator recovers
+	flags.StringVar(&params.FailoverRecoveryPolicy, "failover-recovery-policy", model.FailbackRecoveryPolicy, "Policy applied to failed over clusters when their operator recovers, one of \"failback\" or \"stay\"")
 }
 
 func Initialize(funcs ...func()) {
@@ -84,6 +171,17 @@ func startNewServer() {
 	if err != nil {
 		log.Fatalf("failed to instantiate a server: %v", err)
 	}
+	go config.Watch(ctx, configPath, configWatchInterval, func() {
+		params, err := reloadParams()
+		if err != nil {
+			log.Printf("ignoring configuration reload: %v", err)
+			return
+		}
+		err = newServer.Reload(params)
+		if err != nil {
+			log.Printf("failed to apply reloaded configuration: %v", err)
+		}
+	})
 	err = newServer.Listen(model.PortNumber)
 	if err != nil {
 		log.Fatalf("failed to start server: %v", err)
@@ -91,6 +189,29 @@ func startNewServer() {
 	log.Printf("started server on port %s", model.PortNumber)
 }
 
+// resolves settings again from the command line, environment variables and the configuration file into
+// fresh settings, the settings the sharding manager started with are left untouched
+func reloadParams() (model.ShardingManagerParams, error) {
+	var params model.ShardingManagerParams
+	flags := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	addShardingManagerFlags(flags, &params)
+	// flags set on the command line take precedence over the configuration file
+	for name, value := range commandLineValues {
+		if flags.Lookup(name) == nil {
+			continue
+		}
+		err := flags.Set(name, value)
+		if err != nil {
+			return params, fmt.Errorf("invalid value %q for flag %s: %v", value, name, err)
+		}
+	}
+	err := config.Load(flags, configPath, getSettings(rootCmd))
+	if err != nil {
+		return params, err
+	}
+	return params, config.Validate(&params)
+}
+
 // initialize monitoring
 func initializeMonitoring() {
 	err := monitoring.InitializeMonitoring()
@@ -0,0 +1,132 @@
+/*
+Copyright © 2024 Intuit Inc.
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/manager"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	drainWait         bool
+	drainTimeout      time.Duration
+	drainPollInterval = 5 * time.Second
+)
+
+// operatorCmd represents the operator command
+var operatorCmd = &cobra.Command{
+	Use:   "operator",
+	Short: "Manage scheduling of admiral operators",
+	Long:  `Manage scheduling of admiral operators, cordoned and draining operators do not receive new clusters.`,
+}
+
+var cordonCmd = &cobra.Command{
+	Use:   "cordon <operator identity>",
+	Short: "Stop assigning new clusters to an admiral operator",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setOperatorSchedulingState(mustLoadClients(), args[0], model.CordonedSchedulingState)
+		log.Printf("operator %s cordoned", args[0])
+	},
+}
+
+var uncordonCmd = &cobra.Command{
+	Use:   "uncordon <operator identity>",
+	Short: "Resume assigning clusters to an admiral operator",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setOperatorSchedulingState(mustLoadClients(), args[0], "")
+		log.Printf("operator %s uncordoned", args[0])
+	},
+}
+
+var drainCmd = &cobra.Command{
+	Use:   "drain <operator identity>",
+	Short: "Gradually move all clusters away from an admiral operator",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		clients := mustLoadClients()
+		setOperatorSchedulingState(clients, args[0], model.DrainingSchedulingState)
+		log.Printf("operator %s draining", args[0])
+		if !drainWait {
+			return
+		}
+		err := waitForEmptyShards(clients, args[0])
+		if err != nil {
+			log.Fatalf("operator %s was not drained: %v", args[0], err)
+		}
+		log.Printf("operator %s drained", args[0])
+	},
+}
+
+func init() {
+	addKubeClientFlags(operatorCmd.PersistentFlags(), &smParams)
+	operatorCmd.PersistentFlags().StringVar(&smParams.ShardingManagerIdentity, "shard-identity", "dev", "Identity of the sharding manager instance which distributes load to the operator")
+	operatorCmd.PersistentFlags().StringVar(&smParams.OperatorIdentityLabel, "operator-identity-label", "admiral.io/operatorIdentity", "label used to specify identity of operator for which shard profile is defined")
+	operatorCmd.PersistentFlags().StringVar(&smParams.ShardNamespace, "shard-namespace", "shard-namespace", "Namespace used to create sharding resources")
+	//blocks until the sharding manager has moved all clusters away from the operator
+	drainCmd.Flags().BoolVar(&drainWait, "wait", false, "Wait until all shards of the operator are empty")
+	drainCmd.Flags().DurationVar(&drainTimeout, "timeout", 30*time.Minute, "Maximum time to wait for the operator to be drained")
+
+	operatorCmd.AddCommand(cordonCmd, uncordonCmd, drainCmd)
+	rootCmd.AddCommand(operatorCmd)
+}
+
+// initializes clients used by commands which interact with the cluster directly
+func loadClients() (model.Clients, error) {
+	var clients model.Clients
+	admiralAPIClient, kubernetesClient, err := manager.LoadKubeClients(&manager.KubeClient{}, &smParams)
+	if err != nil {
+		return clients, err
+	}
+	clients.KubernetesClient = kubernetesClient
+	clients.AdmiralClient = admiralAPIClient
+	clients.RegistryClient = registry.NewRegistryClient(registry.WithEndpoint(smParams.RegistryEndpoint))
+	return clients, nil
+}
+
+func mustLoadClients() model.Clients {
+	clients, err := loadClients()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return clients
+}
+
+func setOperatorSchedulingState(clients model.Clients, operatorIdentity string, state string) {
+	err := controller.NewOperatorHandler(clients, &smParams).SetSchedulingState(ctx, operatorIdentity, state)
+	if err != nil {
+		log.Fatalf("failed to update operator %s: %v", operatorIdentity, err)
+	}
+}
+
+// waits until no shard of the operator holds any cluster
+func waitForEmptyShards(clients model.Clients, operatorIdentity string) error {
+	shardHandler := controller.NewShardHandler(clients, &smParams)
+	return wait.PollUntilContextTimeout(ctx, drainPollInterval, drainTimeout, true, func(ctx context.Context) (bool, error) {
+		shards, err := shardHandler.List(ctx)
+		if err != nil {
+			log.Printf("failed to list shards: %v", err)
+			return false, nil
+		}
+		remaining := 0
+		for _, shard := range shards {
+			if shard.Labels[smParams.OperatorIdentityLabel] == operatorIdentity {
+				remaining += len(shard.Spec.Clusters)
+			}
+		}
+		if remaining > 0 {
+			log.Printf("waiting for %d clusters to move away from operator %s", remaining, operatorIdentity)
+		}
+		return remaining == 0, nil
+	})
+}
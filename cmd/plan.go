@@ -0,0 +1,137 @@
+/*
+Copyright © 2024 Intuit Inc.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/manager"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+)
+
+var (
+	planSnapshotPath  string
+	planOperatorsPath string
+	planOperators     []string
+	planOutput        string
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Simulate distribution of configuration amongst admiral operators",
+	Long: `Simulate distribution of configuration amongst admiral operators.
+Registry configuration is loaded from a snapshot file or the registry endpoint and distributed offline using the
+selected strategy, no shard is created or updated.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		clusters, err := loadPlanClusters()
+		if err != nil {
+			log.Fatalf("failed to load registry configuration: %v", err)
+		}
+		operators, err := loadPlanOperators()
+		if err != nil {
+			log.Fatalf("failed to load operators: %v", err)
+		}
+		plan, err := manager.PlanDistribution(&smParams, clusters, operators)
+		if err != nil {
+			log.Fatalf("failed to plan distribution: %v", err)
+		}
+		err = printPlan(cmd.OutOrStdout(), plan, planOutput)
+		if err != nil {
+			log.Fatalf("failed to print plan: %v", err)
+		}
+	},
+}
+
+func init() {
+	planCmd.Flags().StringVar(&planSnapshotPath, "snapshot", "", "Registry snapshot file to load configuration from instead of the registry endpoint")
+	planCmd.Flags().StringVar(&smParams.RegistryEndpoint, "registry-endpoint", "", "Registry Service endpoint to get configuration for sharding manager")
+	planCmd.Flags().StringVar(&smParams.ShardingManagerIdentity, "shard-identity", "dev", "Identity of the sharding manager instance used to get configuration from registry")
+	planCmd.Flags().StringSliceVar(&planOperators, "operators", nil, "Comma separated identities of operators to distribute configuration amongst")
+	planCmd.Flags().StringVar(&planOperatorsPath, "operators-file", "", "YAML or JSON file defining operators with their capacity and locality")
+	planCmd.Flags().StringVar(&smParams.DistributionStrategy, "strategy", model.LeastLoadedStrategy, "Strategy used to distribute clusters amongst operators, one of \"least-loaded\", \"locality-aware\", \"segmented\" or \"dependency-aware\"")
+	planCmd.Flags().StringVar(&smParams.SegmentKey, "segment-key", "segment", "Cluster metadata key partitioning clusters into segments with strategy \"segmented\"")
+	planCmd.Flags().StringToStringVar(&smParams.EnvironmentPools, "environment-pools", nil, "Operator pool handling identities of each environment, e.g. prd=prod,qal=non-prod,e2e=non-prod")
+	planCmd.Flags().StringVar(&smParams.ShardingGranularity, "sharding-granularity", model.ClusterShardingGranularity, "Unit of distribution, one of \"cluster\" or \"identity\"")
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", tableOutput, "Output format, one of \"table\" or \"json\"")
+
+	rootCmd.AddCommand(planCmd)
+}
+
+func loadPlanClusters() ([]registry.ClusterConfig, error) {
+	var clusterConfig registry.ShardClusterConfig
+	if planSnapshotPath == "" {
+		registryClient := registry.NewRegistryClient(registry.WithEndpoint(smParams.RegistryEndpoint))
+		clusterConfig, err := registryClient.BulkSyncByShardingManagerIdentity(ctx, smParams.ShardingManagerIdentity)
+		return clusterConfig.Clusters, err
+	}
+	data, err := os.ReadFile(planSnapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &clusterConfig)
+	if err != nil {
+		return nil, err
+	}
+	return clusterConfig.Clusters, nil
+}
+
+func loadPlanOperators() ([]model.Operator, error) {
+	var operators []model.Operator
+	for _, identity := range planOperators {
+		operators = append(operators, model.Operator{Identity: identity, Capacity: model.DefaultOperatorCapacity})
+	}
+	if planOperatorsPath != "" {
+		defined, err := controller.LoadOperatorsFile(planOperatorsPath)
+		if err != nil {
+			return nil, err
+		}
+		operators = append(operators, defined...)
+	}
+	if len(operators) == 0 {
+		return nil, fmt.Errorf("at least one operator must be provided using --operators or --operators-file")
+	}
+	return operators, nil
+}
+
+func printPlan(out io.Writer, plan model.DistributionPlan, output string) error {
+	switch output {
+	case jsonOutput:
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case tableOutput:
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(writer, "OPERATOR\tLOCALITY\tCAPACITY\tCLUSTERS\tIDENTITIES\tWEIGHTED LOAD\tCROSS LOCALITY\tASSIGNED CLUSTERS\n")
+		for _, operator := range plan.Operators {
+			fmt.Fprintf(writer, "%s\t%s\t%g\t%d\t%d\t%.2f\t%d\t%s\n",
+				operator.Operator, operator.Locality, operator.Capacity, len(operator.Clusters), operator.Identities,
+				operator.WeightedLoad, operator.CrossLocality, strings.Join(operator.Clusters, ","))
+		}
+		fmt.Fprintf(writer, "\nSTRATEGY\tMAX LOAD\tMIN LOAD\tSTDDEV LOAD\tCROSS LOCALITY\tCROSS OPERATOR DEPENDENCIES\n")
+		fmt.Fprintf(writer, "%s\t%.2f\t%.2f\t%.2f\t%d\t%d\n",
+			plan.Strategy, plan.Statistics.MaxLoad, plan.Statistics.MinLoad, plan.Statistics.StdDevLoad, plan.Statistics.CrossLocality,
+			plan.Statistics.CrossOperatorDependencies)
+		return writer.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+}
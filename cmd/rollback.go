@@ -0,0 +1,56 @@
+/*
+Copyright © 2024 Intuit Inc.
+*/
+package cmd
+
+import (
+	"log"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/spf13/cobra"
+)
+
+var rollbackRevision int
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Re-apply a previous revision of the shard assignment",
+	Long: `Re-apply a previous revision of the shard assignment kept in the revision history.
+Clusters are moved back to the operator handling them in that revision by the next sync and automatic rebalancing
+is frozen until it is resumed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if rollbackRevision <= 0 {
+			log.Fatalf("--to-revision must be positive, got %d", rollbackRevision)
+		}
+		err := controller.NewRevisionHandler(mustLoadClients(), &smParams).Freeze(ctx, rollbackRevision)
+		if err != nil {
+			log.Fatalf("failed to roll back to revision %d: %v", rollbackRevision, err)
+		}
+		log.Printf("rolled back to revision %d, rebalancing is frozen until it is resumed", rollbackRevision)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume automatic rebalancing frozen by a rollback",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := controller.NewRevisionHandler(mustLoadClients(), &smParams).Freeze(ctx, 0)
+		if err != nil {
+			log.Fatalf("failed to resume rebalancing: %v", err)
+		}
+		log.Printf("rebalancing resumed")
+	},
+}
+
+func init() {
+	for _, command := range []*cobra.Command{rollbackCmd, resumeCmd} {
+		addKubeClientFlags(command.Flags(), &smParams)
+		command.Flags().StringVar(&smParams.ShardNamespace, "shard-namespace", "shard-namespace", "Namespace used to create sharding resources")
+		command.Flags().StringVar(&smParams.RevisionHistoryConfigMap, "revision-history-configmap", "admiral-sharding-revisions", "ConfigMap in the shard namespace keeping the last revisions of the assignment")
+	}
+	rollbackCmd.Flags().IntVar(&rollbackRevision, "to-revision", 0, "Revision of the revision history to roll back to")
+	_ = rollbackCmd.MarkFlagRequired("to-revision")
+
+	rootCmd.AddCommand(rollbackCmd, resumeCmd)
+}
@@ -6,27 +6,62 @@ package cmd
 import (
 	"log"
 
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/config"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
 
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // maintains sharding manger program arguments
 var smParams = model.ShardingManagerParams{}
 
+// configuration file providing settings not set on the command line
+var configPath string
+
+// settings set on the command line, which keep precedence when the configuration file is reloaded
+var commandLineValues map[string]string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "admiral-sharding-manager",
 	Short: "Sharding manager distributes load among admiral operators",
 	Long:  "Sharding manager distributes load among admiral operators",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if path, ok := os.LookupEnv(config.GetEnvName("config")); ok && !cmd.Flags().Changed("config") {
+			configPath = path
+		}
+		var err error
+		commandLineValues, err = config.GetCommandLineValues(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		return config.Load(cmd.Flags(), configPath, getSettings(cmd.Root()))
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		log.Println("admiral sharding manager has been initialized")
 	},
 }
 
+// flags of the command and its subcommands, a configuration file shared by commands may hold settings of any of them
+func getSettings(cmd *cobra.Command) map[string]bool {
+	settings := make(map[string]bool)
+	addSetting := func(flag *pflag.Flag) {
+		settings[flag.Name] = true
+	}
+	cmd.Flags().VisitAll(addSetting)
+	cmd.PersistentFlags().VisitAll(addSetting)
+	for _, child := range cmd.Commands() {
+		for name := range getSettings(child) {
+			settings[name] = true
+		}
+	}
+	return settings
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -37,4 +72,6 @@ func Execute() {
 }
 
 // manage root command flags
-func init() {}
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "YAML or JSON configuration file keyed by flag name, settings can also be overridden using "+config.EnvPrefix+"<FLAG_NAME> environment variables")
+}
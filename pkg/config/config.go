@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/schedule"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// prefix of environment variables overriding settings, ADMIRAL_SHARDING_MANAGER_SHARD_NAMESPACE overrides shard-namespace
+const EnvPrefix = "ADMIRAL_SHARDING_MANAGER_"
+
+// applies settings from a yaml or json configuration file and from environment variables to flags, keys of the
+// configuration file are flag names. Flags set on the command line take precedence over environment variables
+// which take precedence over the configuration file. A configuration file shared by several commands may hold
+// any of the provided settings, settings which are not flags of the command are ignored
+func Load(flags *pflag.FlagSet, path string, settings map[string]bool) error {
+	values := make(map[string]string)
+	if path != "" {
+		fileValues, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		for name, value := range fileValues {
+			if flags.Lookup(name) != nil {
+				values[name] = value
+				continue
+			}
+			if !settings[name] {
+				return fmt.Errorf("unknown setting %q in configuration file %s", name, path)
+			}
+		}
+	}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		value, ok := os.LookupEnv(GetEnvName(flag.Name))
+		if ok {
+			values[flag.Name] = value
+		}
+	})
+
+	var names []string
+	for name := range values {
+		if !flags.Changed(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := flags.Set(name, values[name])
+		if err != nil {
+			return fmt.Errorf("invalid value %q for setting %s: %v", values[name], name, err)
+		}
+	}
+	return nil
+}
+
+// values of the flags set on the command line, formatted the way they are passed on the command line so that
+// settings can be resolved again into another flag set declaring the same flags
+func GetCommandLineValues(flags *pflag.FlagSet) (map[string]string, error) {
+	var err error
+	values := make(map[string]string)
+	flags.Visit(func(flag *pflag.Flag) {
+		if err == nil {
+			values[flag.Name], err = formatFlagValue(flag.Value)
+		}
+	})
+	return values, err
+}
+
+// slices and maps are formatted as comma separated values instead of their bracketed representation
+func formatFlagValue(value pflag.Value) (string, error) {
+	if slice, ok := value.(pflag.SliceValue); ok {
+		var buffer bytes.Buffer
+		writer := csv.NewWriter(&buffer)
+		err := writer.Write(slice.GetSlice())
+		writer.Flush()
+		return strings.TrimSpace(buffer.String()), err
+	}
+	if value.Type() == "stringToString" {
+		return strings.TrimSuffix(strings.TrimPrefix(value.String(), "["), "]"), nil
+	}
+	return value.String(), nil
+}
+
+// name of the environment variable overriding a flag
+func GetEnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %v", err)
+	}
+	var settings map[string]any
+	err = yaml.Unmarshal(data, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file %s: %v", path, err)
+	}
+	values := make(map[string]string)
+	for name, setting := range settings {
+		value, err := formatValue(setting)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for setting %s in configuration file %s: %v", name, path, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// formats a configuration file value the way it would be passed on the command line
+func formatValue(setting any) (string, error) {
+	switch value := setting.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case map[string]any:
+		var items []string
+		for key, item := range value {
+			formatted, err := formatValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, key+"="+formatted)
+		}
+		sort.Strings(items)
+		return strings.Join(items, ","), nil
+	case []any:
+		var items []string
+		for _, item := range value {
+			formatted, err := formatValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, formatted)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", setting)
+	}
+}
+
+// checks settings of sharding manager, every invalid setting is reported
+func Validate(params *model.ShardingManagerParams) error {
+	var errs []error
+	if params.ShardingManagerIdentity == "" {
+		errs = append(errs, fmt.Errorf("shard-identity must not be empty"))
+	}
+	if params.OperatorIdentityLabel == "" {
+		errs = append(errs, fmt.Errorf("operator-identity-label must not be empty"))
+	}
+	if params.ShardNamespace == "" {
+		errs = append(errs, fmt.Errorf("shard-namespace must not be empty"))
+	}
+	switch params.DistributionStrategy {
+	case "", model.LeastLoadedStrategy, model.LocalityAwareStrategy, model.DependencyAwareStrategy:
+	case model.SegmentedStrategy:
+		if params.SegmentKey == "" {
+			errs = append(errs, fmt.Errorf("segment-key must not be empty with strategy %q", model.SegmentedStrategy))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("strategy %q is not one of %q, %q, %q or %q",
+			params.DistributionStrategy, model.LeastLoadedStrategy, model.LocalityAwareStrategy, model.SegmentedStrategy,
+			model.DependencyAwareStrategy))
+	}
+	for environment, pool := range params.EnvironmentPools {
+		if environment == "" || pool == "" {
+			errs = append(errs, fmt.Errorf("environment-pools must map non empty environments to non empty pools, got %q=%q", environment, pool))
+		}
+	}
+	switch params.ShardingGranularity {
+	case "", model.ClusterShardingGranularity, model.IdentityShardingGranularity:
+	default:
+		errs = append(errs, fmt.Errorf("sharding-granularity %q is not one of %q or %q",
+			params.ShardingGranularity, model.ClusterShardingGranularity, model.IdentityShardingGranularity))
+	}
+	switch params.FailoverRecoveryPolicy {
+	case model.FailbackRecoveryPolicy, model.StayRecoveryPolicy:
+	default:
+		errs = append(errs, fmt.Errorf("failover-recovery-policy %q is not one of %q or %q",
+			params.FailoverRecoveryPolicy, model.FailbackRecoveryPolicy, model.StayRecoveryPolicy))
+	}
+	if params.SyncPeriod <= 0 {
+		errs = append(errs, fmt.Errorf("sync-period must be positive, got %v", params.SyncPeriod))
+	}
+	if params.OperatorGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("operator-grace-period must not be negative, got %v", params.OperatorGracePeriod))
+	}
+	if params.MaxRegistryRemovalPercent < 0 || params.MaxRegistryRemovalPercent > 100 {
+		errs = append(errs, fmt.Errorf("max-registry-removal-percent must be between 0 and 100, got %v", params.MaxRegistryRemovalPercent))
+	}
+	if params.RebalanceThreshold < 0 {
+		errs = append(errs, fmt.Errorf("rebalance-threshold must not be negative, got %v", params.RebalanceThreshold))
+	}
+	if params.RebalanceHysteresis < 0 || (params.RebalanceThreshold > 0 && params.RebalanceHysteresis > params.RebalanceThreshold) {
+		errs = append(errs, fmt.Errorf("rebalance-hysteresis must be between 0 and rebalance-threshold, got %v", params.RebalanceHysteresis))
+	}
+	if _, err := schedule.ParseWindows(params.RebalanceWindows); err != nil {
+		errs = append(errs, fmt.Errorf("rebalance-windows is invalid: %v", err))
+	}
+	if params.RolloutMaxMoves > 0 && params.RolloutInterval <= 0 {
+		errs = append(errs, fmt.Errorf("rollout-interval must be positive when rollout-max-moves is set, got %s", params.RolloutInterval))
+	}
+	// operators are discovered from the operators file as the cluster is not accessed in output-dir mode
+	if (params.OutputDir != "") != (params.OperatorsFile != "") {
+		errs = append(errs, fmt.Errorf("operators-file must be set when output-dir is set and only then"))
+	}
+	if params.HandoffTimeout < 0 {
+		errs = append(errs, fmt.Errorf("handoff-timeout must not be negative, got %v", params.HandoffTimeout))
+	}
+	for name, limit := range map[string]int{
+		"max-clusters-per-shard":   params.MaxClustersPerShard,
+		"max-identities-per-shard": params.MaxIdentitiesPerShard,
+		"max-shard-size-bytes":     params.MaxShardSizeBytes,
+		"drain-batch-size":         params.DrainBatchSize,
+		"audit-log-size":           params.AuditLogSize,
+		"max-registry-removals":    params.MaxRegistryRemovals,
+		"rollout-max-moves":        params.RolloutMaxMoves,
+		"revision-history-limit":   params.RevisionHistoryLimit,
+	} {
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", name, limit))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errors.Join(errs...)
+}
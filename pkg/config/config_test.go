@@ -0,0 +1,230 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/spf13/pflag"
+)
+
+func getTestFlags(params *model.ShardingManagerParams) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringVar(&params.ShardNamespace, "shard-namespace", "shard-namespace", "")
+	flags.StringVar(&params.DistributionStrategy, "strategy", model.LeastLoadedStrategy, "")
+	flags.IntVar(&params.MaxClustersPerShard, "max-clusters-per-shard", 0, "")
+	flags.DurationVar(&params.SyncPeriod, "sync-period", 10*time.Second, "")
+	return flags
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         string
+		env            map[string]string
+		args           []string
+		settings       map[string]bool
+		expectedParams model.ShardingManagerParams
+		expectedError  bool
+	}{
+		{
+			name: "Given no configuration file and no environment variables, " +
+				"When configuration is loaded, " +
+				"Then flag defaults should be used",
+			expectedParams: model.ShardingManagerParams{
+				ShardNamespace:       "shard-namespace",
+				DistributionStrategy: model.LeastLoadedStrategy,
+				SyncPeriod:           10 * time.Second,
+			},
+		},
+		{
+			name: "Given a configuration file, environment variables and command line flags, " +
+				"When configuration is loaded, " +
+				"Then command line flags should take precedence over environment variables over the configuration file",
+			config: "shard-namespace: from-file\nstrategy: locality-aware\nmax-clusters-per-shard: 5\nsync-period: 1m\n",
+			env: map[string]string{
+				"ADMIRAL_SHARDING_MANAGER_SHARD_NAMESPACE":        "from-env",
+				"ADMIRAL_SHARDING_MANAGER_MAX_CLUSTERS_PER_SHARD": "10",
+			},
+			args: []string{"--shard-namespace=from-args"},
+			expectedParams: model.ShardingManagerParams{
+				ShardNamespace:       "from-args",
+				DistributionStrategy: model.LocalityAwareStrategy,
+				MaxClustersPerShard:  10,
+				SyncPeriod:           time.Minute,
+			},
+		},
+		{
+			name: "Given a json configuration file, " +
+				"When configuration is loaded, " +
+				"Then settings should be applied",
+			config: `{"max-clusters-per-shard": 3}`,
+			expectedParams: model.ShardingManagerParams{
+				ShardNamespace:       "shard-namespace",
+				DistributionStrategy: model.LeastLoadedStrategy,
+				MaxClustersPerShard:  3,
+				SyncPeriod:           10 * time.Second,
+			},
+		},
+		{
+			name: "Given a configuration file shared with other commands, " +
+				"When configuration is loaded, " +
+				"Then settings of other commands should be ignored",
+			config:   "max-clusters-per-shard: 3\noutput: json\n",
+			settings: map[string]bool{"output": true},
+			expectedParams: model.ShardingManagerParams{
+				ShardNamespace:       "shard-namespace",
+				DistributionStrategy: model.LeastLoadedStrategy,
+				MaxClustersPerShard:  3,
+				SyncPeriod:           10 * time.Second,
+			},
+		},
+		{
+			name: "Given a configuration file with an unknown setting, " +
+				"When configuration is loaded, " +
+				"Then there should be non nil error",
+			config:        "unknown: value\n",
+			settings:      map[string]bool{"output": true},
+			expectedError: true,
+		},
+		{
+			name: "Given an environment variable with an invalid value, " +
+				"When configuration is loaded, " +
+				"Then there should be non nil error",
+			env:           map[string]string{"ADMIRAL_SHARDING_MANAGER_SYNC_PERIOD": "often"},
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var path string
+			if c.config != "" {
+				path = filepath.Join(t.TempDir(), "config.yaml")
+				err := os.WriteFile(path, []byte(c.config), 0644)
+				if err != nil {
+					t.Fatalf("failed to write configuration file: %v", err)
+				}
+			}
+			for name, value := range c.env {
+				t.Setenv(name, value)
+			}
+			var params model.ShardingManagerParams
+			flags := getTestFlags(&params)
+			err := flags.Parse(c.args)
+			if err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			err = Load(flags, path, c.settings)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error while loading configuration")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error while loading configuration: %v", err)
+			}
+			if !cmp.Equal(params, c.expectedParams) {
+				t.Errorf(cmp.Diff(params, c.expectedParams))
+			}
+		})
+	}
+}
+
+func TestGetCommandLineValues(t *testing.T) {
+	var params model.ShardingManagerParams
+	flags := getTestFlags(&params)
+	flags.StringSliceVar(&params.ImpersonateGroups, "as-group", nil, "")
+	flags.StringToStringVar(&params.EnvironmentPools, "environment-pools", nil, "")
+	err := flags.Parse([]string{"--strategy=segmented", "--as-group=admins,viewers", "--environment-pools=prd=prod,qal=non-prod"})
+	if err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	values, err := GetCommandLineValues(flags)
+	if err != nil {
+		t.Fatalf("unexpected error while reading command line values: %v", err)
+	}
+
+	// values resolved into another flag set are the values set on the command line
+	var resolved model.ShardingManagerParams
+	resolvedFlags := getTestFlags(&resolved)
+	resolvedFlags.StringSliceVar(&resolved.ImpersonateGroups, "as-group", nil, "")
+	resolvedFlags.StringToStringVar(&resolved.EnvironmentPools, "environment-pools", nil, "")
+	for name, value := range values {
+		err = resolvedFlags.Set(name, value)
+		if err != nil {
+			t.Fatalf("failed to set flag %s to %q: %v", name, value, err)
+		}
+	}
+	if len(values) != 3 {
+		t.Errorf("expected values of the 3 flags set on the command line, got %v", values)
+	}
+	if !cmp.Equal(resolved, params) {
+		t.Errorf(cmp.Diff(resolved, params))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validParams := model.ShardingManagerParams{
+		ShardingManagerIdentity: "dev",
+		OperatorIdentityLabel:   "admiral.io/operatorIdentity",
+		ShardNamespace:          "shard-namespace",
+		DistributionStrategy:    model.LeastLoadedStrategy,
+		FailoverRecoveryPolicy:  model.FailbackRecoveryPolicy,
+		SyncPeriod:              10 * time.Second,
+	}
+	invalidParams := validParams
+	invalidParams.DistributionStrategy = "random"
+	invalidParams.SyncPeriod = 0
+	invalidParams.DrainBatchSize = -1
+	invalidParams.MaxRegistryRemovalPercent = 150
+	invalidParams.RolloutMaxMoves = 5
+	invalidParams.RebalanceWindows = "0 2 * * *"
+	invalidParams.RebalanceThreshold = 0.5
+	invalidParams.RebalanceHysteresis = 1
+	invalidParams.RevisionHistoryLimit = -1
+	invalidParams.OperatorsFile = "operators.yaml"
+
+	testCases := []struct {
+		name          string
+		params        model.ShardingManagerParams
+		expectedError string
+	}{
+		{
+			name: "Given valid settings, " +
+				"When settings are validated, " +
+				"Then there should be no error",
+			params: validParams,
+		},
+		{
+			name: "Given several invalid settings, " +
+				"When settings are validated, " +
+				"Then every invalid setting should be reported",
+			params: invalidParams,
+			expectedError: "drain-batch-size must not be negative, got -1\n" +
+				"max-registry-removal-percent must be between 0 and 100, got 150\n" +
+				"operators-file must be set when output-dir is set and only then\n" +
+				"rebalance-hysteresis must be between 0 and rebalance-threshold, got 1\n" +
+				"rebalance-windows is invalid: window \"0 2 * * *\" must consist of five cron fields and a duration\n" +
+				"revision-history-limit must not be negative, got -1\n" +
+				"rollout-interval must be positive when rollout-max-moves is set, got 0s\n" +
+				"strategy \"random\" is not one of \"least-loaded\", \"locality-aware\", \"segmented\" or \"dependency-aware\"\n" +
+				"sync-period must be positive, got 0s",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var actualError string
+			err := Validate(&c.params)
+			if err != nil {
+				actualError = err.Error()
+			}
+			if actualError != c.expectedError {
+				t.Errorf(cmp.Diff(actualError, c.expectedError))
+			}
+		})
+	}
+}
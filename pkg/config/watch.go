@@ -0,0 +1,46 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// invokes reload whenever the configuration file changes or the process receives SIGHUP, the configuration file
+// is checked for changes every interval
+func Watch(ctx context.Context, path string, interval time.Duration, reload func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	modified := getModTime(path)
+	for {
+		select {
+		case <-signals:
+			reload()
+		case <-ticker.C:
+			if path == "" {
+				continue
+			}
+			updated := getModTime(path)
+			if !updated.Equal(modified) {
+				modified = updated
+				reload()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func getModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
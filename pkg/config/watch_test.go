@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("sync-period: 10s\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write configuration file: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan struct{}, 10)
+	stopped := make(chan struct{})
+	go func() {
+		Watch(ctx, path, 10*time.Millisecond, func() {
+			reloads <- struct{}{}
+		})
+		close(stopped)
+	}()
+
+	// an unchanged configuration file is not reloaded
+	select {
+	case <-reloads:
+		t.Fatalf("expected no reload of an unchanged configuration file")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	modified := time.Now().Add(time.Minute)
+	err = os.Chtimes(path, modified, modified)
+	if err != nil {
+		t.Fatalf("failed to modify configuration file: %v", err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a reload once the configuration file changed")
+	}
+	select {
+	case <-reloads:
+		t.Fatalf("expected a single reload for a single change")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected watch to stop once its context is done")
+	}
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"sigs.k8s.io/yaml"
+)
+
+// operators defined in a file do not maintain a heartbeat, they are considered healthy as long as they are
+// defined, for this long after every listing
+const fileOperatorLeaseDuration = 24 * time.Hour
+
+// operator defined in an operators file
+type operatorDefinition struct {
+	Identity        string  `json:"identity"`
+	Capacity        float64 `json:"capacity,omitempty"`
+	Locality        string  `json:"locality,omitempty"`
+	Segment         string  `json:"segment,omitempty"`
+	Pool            string  `json:"pool,omitempty"`
+	SchedulingState string  `json:"schedulingState,omitempty"`
+}
+
+type operatorDefinitionList struct {
+	Operators []operatorDefinition `json:"operators"`
+}
+
+// discovers admiral operators from a file instead of their heartbeat lease, used when shards are rendered
+// to a directory and sharding manager does not access the cluster
+type fileOperatorHandler struct {
+	*handlerParams
+}
+
+// initializes FileOperatorHandler which reads operators from the configured operators file
+func NewFileOperatorHandler(smParams *model.ShardingManagerParams) *fileOperatorHandler {
+	return &fileOperatorHandler{
+		handlerParams: newHandlerParams(smParams),
+	}
+}
+
+// operators are read again on every listing so that changes to the file are picked up by the next sync
+func (fh *fileOperatorHandler) List(ctx context.Context) ([]model.Operator, error) {
+	operators, err := LoadOperatorsFile(fh.params().OperatorsFile)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range operators {
+		operators[i].LastHeartbeat = now
+		operators[i].LeaseDuration = fileOperatorLeaseDuration
+	}
+	return operators, nil
+}
+
+func (fh *fileOperatorHandler) SetSchedulingState(ctx context.Context, operatorIdentity string, state string) error {
+	return fmt.Errorf("scheduling state of operator %s must be set in operators file %s", operatorIdentity, fh.params().OperatorsFile)
+}
+
+// reads operators with their capacity, locality, segment, pool and scheduling state from a yaml or json file
+func LoadOperatorsFile(path string) ([]model.Operator, error) {
+	var (
+		definitions operatorDefinitionList
+		operators   []model.Operator
+	)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operators file: %v", err)
+	}
+	err = yaml.UnmarshalStrict(data, &definitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse operators file: %v", err)
+	}
+	for _, definition := range definitions.Operators {
+		if definition.Identity == "" {
+			return nil, fmt.Errorf("operators file %s defines an operator without identity", path)
+		}
+		operators = append(operators, model.Operator{
+			Identity:        definition.Identity,
+			Capacity:        definition.Capacity,
+			Locality:        definition.Locality,
+			Segment:         definition.Segment,
+			Pool:            definition.Pool,
+			SchedulingState: definition.SchedulingState,
+		})
+	}
+	return operators, nil
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+)
+
+func TestFileOperatorHandlerList(t *testing.T) {
+	testCases := []struct {
+		name              string
+		data              *string
+		expectedOperators []model.Operator
+		expectedError     string
+	}{
+		{
+			name: "Given an operators file, " +
+				"When operators are listed, " +
+				"Then every operator should be returned as defined",
+			data: ptr("operators:\n" +
+				"- identity: operator1\n  capacity: 2\n  locality: us-west-2\n" +
+				"- identity: operator2\n  segment: payments\n  pool: pool1\n  schedulingState: cordoned\n"),
+			expectedOperators: []model.Operator{
+				{Identity: "operator1", Capacity: 2, Locality: "us-west-2"},
+				{Identity: "operator2", Segment: "payments", Pool: "pool1", SchedulingState: model.CordonedSchedulingState},
+			},
+		},
+		{
+			name: "Given an operators file defining an operator without identity, " +
+				"When operators are listed, " +
+				"Then an error should be returned",
+			data:          ptr("operators:\n- capacity: 2\n"),
+			expectedError: "operators file ",
+		},
+		{
+			name: "Given an operators file with an unknown field, " +
+				"When operators are listed, " +
+				"Then an error should be returned",
+			data:          ptr("operators:\n- name: operator1\n"),
+			expectedError: "failed to parse operators file: ",
+		},
+		{
+			name: "Given no operators file, " +
+				"When operators are listed, " +
+				"Then an error should be returned",
+			expectedError: "failed to read operators file: ",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			params := &model.ShardingManagerParams{OperatorsFile: filepath.Join(t.TempDir(), "operators.yaml")}
+			if c.data != nil {
+				err := os.WriteFile(params.OperatorsFile, []byte(*c.data), 0o644)
+				if err != nil {
+					t.Fatalf("failed to write operators file: %v", err)
+				}
+			}
+			operators, err := NewFileOperatorHandler(params).List(context.Background())
+			var actualError string
+			if err != nil {
+				actualError = err.Error()
+			}
+			if !strings.HasPrefix(actualError, c.expectedError) || (c.expectedError == "") != (actualError == "") {
+				t.Errorf("expected error starting with %q, got %q", c.expectedError, actualError)
+			}
+			// operators defined in a file are healthy whenever they are listed
+			for i := range operators {
+				if time.Since(operators[i].LastHeartbeat) > time.Minute || operators[i].LeaseDuration != fileOperatorLeaseDuration {
+					t.Errorf("expected operator %s to be healthy, got heartbeat %s and lease duration %s",
+						operators[i].Identity, operators[i].LastHeartbeat, operators[i].LeaseDuration)
+				}
+				operators[i].LastHeartbeat = time.Time{}
+				operators[i].LeaseDuration = 0
+			}
+			if !cmp.Equal(operators, c.expectedOperators) {
+				t.Errorf(cmp.Diff(operators, c.expectedOperators))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+
+	log "github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const manifestExtension = ".yaml"
+
+// manages shards as yaml manifests in a directory instead of resources on a kubernetes cluster,
+// manifests are meant to be committed to a gitops repository
+type fileShardHandler struct {
+	*handlerParams
+}
+
+// initializes FileShardHandler which renders shards to the configured output directory
+func NewFileShardHandler(smParams *model.ShardingManagerParams) *fileShardHandler {
+	return &fileShardHandler{
+		handlerParams: newHandlerParams(smParams),
+	}
+}
+
+func (fh *fileShardHandler) Create(
+	ctx context.Context,
+	clusterConfiguration []registry.ClusterConfig,
+	shardName string,
+	operatorIdentity string) (*typeV1.Shard, error) {
+	_, err := os.Stat(fh.getManifestPath(shardName))
+	if err == nil {
+		return nil, k8sErrors.NewAlreadyExists(typeV1.Resource("shards"), shardName)
+	}
+	shard := buildShardResource(clusterConfiguration, fh.params(), shardName, operatorIdentity)
+	return shard, fh.write(shard)
+}
+
+func (fh *fileShardHandler) Update(
+	ctx context.Context,
+	clusterConfiguration []registry.ClusterConfig,
+	shardName string,
+	operatorIdentity string) (*typeV1.Shard, error) {
+	shard := buildShardResource(clusterConfiguration, fh.params(), shardName, operatorIdentity)
+	return shard, fh.write(shard)
+}
+
+func (fh *fileShardHandler) Delete(ctx context.Context, shard *typeV1.Shard) error {
+	err := os.Remove(fh.getManifestPath(shard.Name))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete shard manifest: %v", err)
+	}
+	return nil
+}
+
+func (fh *fileShardHandler) List(ctx context.Context) ([]typeV1.Shard, error) {
+	var shards []typeV1.Shard
+	entries, err := os.ReadDir(fh.params().OutputDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return shards, nil
+		}
+		return nil, fmt.Errorf("failed to list shard manifests: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), manifestExtension) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(fh.params().OutputDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read shard manifest %s: %v", entry.Name(), err)
+		}
+		var shard typeV1.Shard
+		err = yaml.Unmarshal(data, &shard)
+		if err != nil {
+			log.Warnf("skipping file %s which is not a shard manifest: %v", entry.Name(), err)
+			continue
+		}
+		if shard.Kind != ShardKind || shard.Labels[ShardIdentity] != fh.params().ShardingManagerIdentity {
+			continue
+		}
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+func (fh *fileShardHandler) Partition(clusterConfiguration []registry.ClusterConfig, operatorIdentity string) [][]registry.ClusterConfig {
+	return partitionClusterConfigs(clusterConfiguration, fh.params(), operatorIdentity)
+}
+
+// writes shard manifest, the file is left untouched when its content did not change
+func (fh *fileShardHandler) write(shard *typeV1.Shard) error {
+	manifest, err := RenderShardManifest(shard)
+	if err != nil {
+		return err
+	}
+	path := fh.getManifestPath(shard.Name)
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, manifest) {
+		return nil
+	}
+	err = os.MkdirAll(fh.params().OutputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	err = os.WriteFile(path, manifest, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write shard manifest: %v", err)
+	}
+	return nil
+}
+
+func (fh *fileShardHandler) getManifestPath(shardName string) string {
+	return filepath.Join(fh.params().OutputDir, shardName+manifestExtension)
+}
+
+// renders shard as a yaml manifest with sorted keys, server populated fields and status are omitted
+func RenderShardManifest(shard *typeV1.Shard) ([]byte, error) {
+	metadata := map[string]any{
+		"name":      shard.Name,
+		"namespace": shard.Namespace,
+	}
+	if len(shard.Labels) > 0 {
+		metadata["labels"] = shard.Labels
+	}
+	if len(shard.Annotations) > 0 {
+		metadata["annotations"] = shard.Annotations
+	}
+	manifest := map[string]any{
+		"apiVersion": typeV1.SchemeGroupVersion.String(),
+		"kind":       ShardKind,
+		"metadata":   metadata,
+		"spec":       shard.Spec,
+	}
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render shard manifest: %v", err)
+	}
+	return data, nil
+}
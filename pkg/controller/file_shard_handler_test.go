@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+func TestFileShardHandler(t *testing.T) {
+	params := &model.ShardingManagerParams{
+		ShardingManagerIdentity: "dev",
+		OperatorIdentityLabel:   "admiral.io/operatorIdentity",
+		ShardNamespace:          "shard-namespace",
+		OutputDir:               t.TempDir(),
+	}
+	fileShardHandler := NewFileShardHandler(params)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name              string
+		clusters          []registry.ClusterConfig
+		expectedManifest  string
+		expectedUnchanged bool
+	}{
+		{
+			name: "Given clusters in random order, " +
+				"When shard is rendered, " +
+				"Then manifest should be written with sorted clusters and identities",
+			clusters: []registry.ClusterConfig{getTestClusterConfig("cluster2", 1), getTestClusterConfig("cluster1", 2)},
+			expectedManifest: `apiVersion: admiral.io/v1
+kind: Shard
+metadata:
+  labels:
+    admiral.io/operatorIdentity: operator1
+    admiral.io/shardIdentity: dev
+  name: shard-operator1-0
+  namespace: shard-namespace
+spec:
+  clusters:
+  - identities:
+    - environment: qal
+      name: cluster1-identity0
+    - environment: qal
+      name: cluster1-identity1
+    locality: us-west-2
+    name: cluster1
+  - identities:
+    - environment: qal
+      name: cluster2-identity0
+    locality: us-west-2
+    name: cluster2
+`,
+		},
+		{
+			name: "Given the same clusters in another order, " +
+				"When shard is rendered again, " +
+				"Then manifest should not be rewritten",
+			clusters:          []registry.ClusterConfig{getTestClusterConfig("cluster1", 2), getTestClusterConfig("cluster2", 1)},
+			expectedUnchanged: true,
+		},
+	}
+	path := filepath.Join(params.OutputDir, "shard-operator1-0.yaml")
+	var previous os.FileInfo
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := fileShardHandler.Update(ctx, c.clusters, "shard-operator1-0", "operator1")
+			if err != nil {
+				t.Fatalf("unexpected error while rendering shard: %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("expected shard manifest to be written: %v", err)
+			}
+			if c.expectedUnchanged {
+				if !info.ModTime().Equal(previous.ModTime()) {
+					t.Errorf("expected unchanged shard manifest not to be rewritten")
+				}
+				return
+			}
+			previous = info
+			data, _ := os.ReadFile(path)
+			if !cmp.Equal(string(data), c.expectedManifest) {
+				t.Errorf(cmp.Diff(string(data), c.expectedManifest))
+			}
+		})
+	}
+
+	shards, err := fileShardHandler.List(ctx)
+	if err != nil || len(shards) != 1 || shards[0].Name != "shard-operator1-0" {
+		t.Fatalf("expected rendered shard to be listed, got %v, %v", shards, err)
+	}
+	err = fileShardHandler.Delete(ctx, &shards[0])
+	if err != nil {
+		t.Fatalf("unexpected error while deleting shard: %v", err)
+	}
+	shards, _ = fileShardHandler.List(ctx)
+	if len(shards) != 0 {
+		t.Errorf("expected deleted shard not to be listed")
+	}
+}
@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+var (
+	shardingManagerControllerMeter = monitoring.NewMeter("admiral_sharding_manager_controller")
+	shardTargetRequestsTotal       = monitoring.NewCounter(
+		"shard_target_requests_total",
+		"total number of shard requests sent to each shard target",
+		monitoring.WithMeter(shardingManagerControllerMeter))
+
+	// backoff applied to shard requests which failed with a transient error
+	shardTargetBackoff = wait.Backoff{
+		Steps:    4,
+		Duration: 100 * time.Millisecond,
+		Factor:   2.0,
+		Jitter:   0.1,
+	}
+)
+
+// Interface to route shards of each operator to the cluster the operator runs in
+type ShardRouter interface {
+	// resolves the shard targets again so that targets added or removed since the last sync are picked up
+	LoadTargets(ctx context.Context) error
+	// updates the shard target of every operator
+	RouteOperators(operators []model.Operator)
+	// health of every shard target
+	GetTargetStatus() []model.ShardTargetStatus
+}
+
+// loads the handlers of the additional shard targets keyed by target name, the local target is not included
+type ShardTargetLoader func(ctx context.Context, smParams *model.ShardingManagerParams) (map[string]ShardInterface, error)
+
+type shardTarget struct {
+	handler ShardInterface
+	status  model.ShardTargetStatus
+	// shards found on the target by the last successful list, nil until the target was listed
+	shards []typeV1.Shard
+}
+
+// publishes shards to multiple clusters, shards of an operator are published to the target it declares
+// using the operator target label and to the local target otherwise
+type multiClusterShardHandler struct {
+	*handlerParams
+	targets map[string]*shardTarget
+	loader  ShardTargetLoader
+	mutex   sync.Mutex
+	// target each operator runs in
+	operatorTargets map[string]string
+	// targets each shard was found on by the last list
+	shardLocations map[string][]string
+}
+
+// initializes MultiClusterShardHandler, targets are keyed by name and must include the local target. Additional
+// targets are resolved again by the loader on every sync when one is provided
+func NewMultiClusterShardHandler(targets map[string]ShardInterface, loader ShardTargetLoader, smParams *model.ShardingManagerParams) *multiClusterShardHandler {
+	handler := &multiClusterShardHandler{
+		handlerParams:   newHandlerParams(smParams),
+		targets:         make(map[string]*shardTarget),
+		loader:          loader,
+		operatorTargets: make(map[string]string),
+		shardLocations:  make(map[string][]string),
+	}
+	for name, target := range targets {
+		handler.targets[name] = &shardTarget{
+			handler: target,
+			status:  model.ShardTargetStatus{Name: name, Healthy: true},
+		}
+	}
+	return handler
+}
+
+// targets build and partition shards with the settings of the handler
+func (mh *multiClusterShardHandler) UpdateParams(smParams *model.ShardingManagerParams) {
+	mh.handlerParams.UpdateParams(smParams)
+	mh.mutex.Lock()
+	defer mh.mutex.Unlock()
+	for _, target := range mh.targets {
+		if updater, ok := target.handler.(ParamsUpdater); ok {
+			updater.UpdateParams(smParams)
+		}
+	}
+}
+
+// replaces the additional targets by the ones resolved by the loader, targets which are kept keep their health
+// and last known shards. Current targets are kept when they cannot be resolved
+func (mh *multiClusterShardHandler) LoadTargets(ctx context.Context) error {
+	if mh.loader == nil {
+		return nil
+	}
+	smParams := mh.params()
+	loaded, err := mh.loader(ctx, smParams)
+	if err != nil {
+		return fmt.Errorf("failed to load shard targets: %v", err)
+	}
+	mh.mutex.Lock()
+	defer mh.mutex.Unlock()
+	targets := map[string]*shardTarget{model.LocalShardTarget: mh.targets[model.LocalShardTarget]}
+	for name, handler := range loaded {
+		if updater, ok := handler.(ParamsUpdater); ok {
+			updater.UpdateParams(smParams)
+		}
+		target, ok := mh.targets[name]
+		if !ok {
+			log.Infof("added shard target %s", name)
+			target = &shardTarget{status: model.ShardTargetStatus{Name: name, Healthy: true}}
+		}
+		target.handler = handler
+		targets[name] = target
+	}
+	for name := range mh.targets {
+		if _, ok := targets[name]; !ok {
+			log.Warnf("removed shard target %s, its shards are no longer reconciled", name)
+		}
+	}
+	mh.targets = targets
+	return nil
+}
+
+func (mh *multiClusterShardHandler) RouteOperators(operators []model.Operator) {
+	mh.mutex.Lock()
+	defer mh.mutex.Unlock()
+	mh.operatorTargets = make(map[string]string)
+	for _, operator := range operators {
+		if operator.Target != "" {
+			mh.operatorTargets[operator.Identity] = operator.Target
+		}
+	}
+}
+
+func (mh *multiClusterShardHandler) GetTargetStatus() []model.ShardTargetStatus {
+	mh.mutex.Lock()
+	defer mh.mutex.Unlock()
+	var statuses []model.ShardTargetStatus
+	for _, target := range mh.targets {
+		statuses = append(statuses, target.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+func (mh *multiClusterShardHandler) Create(
+	ctx context.Context,
+	clusterConfiguration []registry.ClusterConfig,
+	shardName string,
+	operatorIdentity string) (*typeV1.Shard, error) {
+	var shard *typeV1.Shard
+	name, err := mh.getOperatorTarget(operatorIdentity)
+	if err != nil {
+		return nil, err
+	}
+	err = mh.call(name, "create", func(target ShardInterface) error {
+		shard, err = target.Create(ctx, clusterConfiguration, shardName, operatorIdentity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	mh.deleteFromOtherTargets(ctx, shardName, name)
+	return shard, nil
+}
+
+func (mh *multiClusterShardHandler) Update(
+	ctx context.Context,
+	clusterConfiguration []registry.ClusterConfig,
+	shardName string,
+	operatorIdentity string) (*typeV1.Shard, error) {
+	var shard *typeV1.Shard
+	name, err := mh.getOperatorTarget(operatorIdentity)
+	if err != nil {
+		return nil, err
+	}
+	err = mh.call(name, "update", func(target ShardInterface) error {
+		shard, err = target.Update(ctx, clusterConfiguration, shardName, operatorIdentity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	mh.deleteFromOtherTargets(ctx, shardName, name)
+	return shard, nil
+}
+
+// deletes the shard from every target it was found on by the last list
+func (mh *multiClusterShardHandler) Delete(ctx context.Context, shard *typeV1.Shard) error {
+	mh.mutex.Lock()
+	locations := mh.shardLocations[shard.Name]
+	mh.mutex.Unlock()
+	if len(locations) == 0 {
+		locations = []string{model.LocalShardTarget}
+	}
+	var errs []error
+	for _, name := range locations {
+		errs = append(errs, mh.delete(ctx, shard, name))
+	}
+	return errors.Join(errs...)
+}
+
+// lists shards on every target, the last known shards of a target which cannot be reached are used so that its
+// shards are not mistaken for missing ones while shards on other targets are still reconciled. Listing fails
+// when an unreachable target was never listed
+func (mh *multiClusterShardHandler) List(ctx context.Context) ([]typeV1.Shard, error) {
+	var (
+		shards    []typeV1.Shard
+		locations = make(map[string][]string)
+	)
+	for _, name := range mh.getTargetNames() {
+		var targetShards []typeV1.Shard
+		err := mh.call(name, "list", func(target ShardInterface) error {
+			var err error
+			targetShards, err = target.List(ctx)
+			return err
+		})
+		mh.mutex.Lock()
+		target, ok := mh.targets[name]
+		if ok && err == nil {
+			target.shards = append([]typeV1.Shard{}, targetShards...)
+		} else if ok && target.shards != nil {
+			log.Errorf("using last known shards of unreachable shard target %s: %v", name, err)
+			targetShards = target.shards
+			err = nil
+		}
+		mh.mutex.Unlock()
+		// the target was removed while listing
+		if !ok {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list shards of shard target %s: %v", name, err)
+		}
+		for _, shard := range targetShards {
+			if len(locations[shard.Name]) == 0 {
+				shards = append(shards, shard)
+			}
+			locations[shard.Name] = append(locations[shard.Name], name)
+		}
+	}
+	mh.mutex.Lock()
+	mh.shardLocations = locations
+	mh.mutex.Unlock()
+	return shards, nil
+}
+
+func (mh *multiClusterShardHandler) Partition(clusterConfiguration []registry.ClusterConfig, operatorIdentity string) [][]registry.ClusterConfig {
+	return partitionClusterConfigs(clusterConfiguration, mh.params(), operatorIdentity)
+}
+
+// removes copies of a shard left on targets its operator no longer runs in
+func (mh *multiClusterShardHandler) deleteFromOtherTargets(ctx context.Context, shardName string, targetName string) {
+	mh.mutex.Lock()
+	locations := mh.shardLocations[shardName]
+	mh.mutex.Unlock()
+	for _, name := range locations {
+		if name == targetName {
+			continue
+		}
+		log.Infof("deleting shard %s from shard target %s as its operator moved to shard target %s", shardName, name, targetName)
+		err := mh.delete(ctx, &typeV1.Shard{ObjectMeta: metav1.ObjectMeta{Name: shardName, Namespace: mh.params().ShardNamespace}}, name)
+		if err != nil {
+			log.Errorf("failed to delete shard %s from shard target %s: %v", shardName, name, err)
+		}
+	}
+}
+
+func (mh *multiClusterShardHandler) delete(ctx context.Context, shard *typeV1.Shard, name string) error {
+	return mh.call(name, "delete", func(target ShardInterface) error {
+		err := target.Delete(ctx, shard)
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (mh *multiClusterShardHandler) getOperatorTarget(operatorIdentity string) (string, error) {
+	mh.mutex.Lock()
+	defer mh.mutex.Unlock()
+	name, ok := mh.operatorTargets[operatorIdentity]
+	if !ok {
+		name = model.LocalShardTarget
+	}
+	if _, ok := mh.targets[name]; !ok {
+		return "", fmt.Errorf("operator %s runs in unknown shard target %s", operatorIdentity, name)
+	}
+	return name, nil
+}
+
+func (mh *multiClusterShardHandler) getTargetNames() []string {
+	mh.mutex.Lock()
+	defer mh.mutex.Unlock()
+	var names []string
+	for name := range mh.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sends a request to a target retrying transient errors, and records the outcome in the target's health
+func (mh *multiClusterShardHandler) call(name string, operation string, request func(target ShardInterface) error) error {
+	mh.mutex.Lock()
+	target, ok := mh.targets[name]
+	var handler ShardInterface
+	if ok {
+		handler = target.handler
+	}
+	mh.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown shard target %s", name)
+	}
+	err := retry.OnError(shardTargetBackoff, isTransientError, func() error {
+		return request(handler)
+	})
+
+	result := "success"
+	mh.mutex.Lock()
+	if err != nil && !k8sErrors.IsAlreadyExists(err) {
+		result = "failure"
+		target.status.Healthy = false
+		target.status.ConsecutiveFailures++
+		target.status.LastError = err.Error()
+	} else {
+		target.status.Healthy = true
+		target.status.ConsecutiveFailures = 0
+		target.status.LastError = ""
+		target.status.LastSuccess = time.Now()
+	}
+	mh.mutex.Unlock()
+	shardTargetRequestsTotal.Increment(api.WithAttributes(
+		attribute.Key("target").String(name),
+		attribute.Key("operation").String(operation),
+		attribute.Key("result").String(result),
+	))
+	return err
+}
+
+// errors which are expected to be resolved by retrying the same request
+func isTransientError(err error) bool {
+	return !k8sErrors.IsAlreadyExists(err) &&
+		!k8sErrors.IsNotFound(err) &&
+		!k8sErrors.IsInvalid(err) &&
+		!k8sErrors.IsBadRequest(err) &&
+		!k8sErrors.IsForbidden(err) &&
+		!k8sErrors.IsUnauthorized(err)
+}
@@ -0,0 +1,332 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// keeps shards in memory, every request fails when err is set
+type fakeShardHandler struct {
+	params *model.ShardingManagerParams
+	shards map[string]*typeV1.Shard
+	err    error
+}
+
+func newFakeShardHandler(params *model.ShardingManagerParams) *fakeShardHandler {
+	return &fakeShardHandler{params: params, shards: make(map[string]*typeV1.Shard)}
+}
+
+func (fh *fakeShardHandler) Create(ctx context.Context, clusters []registry.ClusterConfig, shardName string, operatorIdentity string) (*typeV1.Shard, error) {
+	if fh.err != nil {
+		return nil, fh.err
+	}
+	if _, ok := fh.shards[shardName]; ok {
+		return nil, k8sErrors.NewAlreadyExists(schema.GroupResource{Resource: "shards"}, shardName)
+	}
+	fh.shards[shardName] = buildShardResource(clusters, fh.params, shardName, operatorIdentity)
+	return fh.shards[shardName], nil
+}
+
+func (fh *fakeShardHandler) Update(ctx context.Context, clusters []registry.ClusterConfig, shardName string, operatorIdentity string) (*typeV1.Shard, error) {
+	if fh.err != nil {
+		return nil, fh.err
+	}
+	fh.shards[shardName] = buildShardResource(clusters, fh.params, shardName, operatorIdentity)
+	return fh.shards[shardName], nil
+}
+
+func (fh *fakeShardHandler) Delete(ctx context.Context, shard *typeV1.Shard) error {
+	if fh.err != nil {
+		return fh.err
+	}
+	if _, ok := fh.shards[shard.Name]; !ok {
+		return k8sErrors.NewNotFound(schema.GroupResource{Resource: "shards"}, shard.Name)
+	}
+	delete(fh.shards, shard.Name)
+	return nil
+}
+
+func (fh *fakeShardHandler) List(ctx context.Context) ([]typeV1.Shard, error) {
+	if fh.err != nil {
+		return nil, fh.err
+	}
+	var shards []typeV1.Shard
+	for _, shard := range fh.shards {
+		shards = append(shards, *shard)
+	}
+	return shards, nil
+}
+
+func (fh *fakeShardHandler) Partition(clusters []registry.ClusterConfig, operatorIdentity string) [][]registry.ClusterConfig {
+	return partitionClusterConfigs(clusters, fh.params, operatorIdentity)
+}
+
+func (fh *fakeShardHandler) getShardNames() []string {
+	names := []string{}
+	for name := range fh.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMultiClusterShardHandler(t *testing.T) {
+	params := &model.ShardingManagerParams{ShardNamespace: "shard-namespace"}
+	ctx := context.Background()
+	clusters := []registry.ClusterConfig{getTestClusterConfig("cluster1", 1)}
+
+	testCases := []struct {
+		name             string
+		operators        []model.Operator
+		existing         map[string][]string
+		unreachable      string
+		shards           map[string]string
+		expectedShards   map[string][]string
+		expectedStatuses map[string]bool
+		expectedError    bool
+	}{
+		{
+			name: "Given operators running in different shard targets, " +
+				"When shards are created, " +
+				"Then each shard should be published to the target of its operator",
+			operators: []model.Operator{{Identity: "operator1"}, {Identity: "operator2", Target: "us-east"}},
+			shards:    map[string]string{"shard-operator1-0": "operator1", "shard-operator2-0": "operator2"},
+			expectedShards: map[string][]string{
+				model.LocalShardTarget: {"shard-operator1-0"},
+				"us-east":              {"shard-operator2-0"},
+			},
+			expectedStatuses: map[string]bool{model.LocalShardTarget: true, "us-east": true},
+		},
+		{
+			name: "Given a shard target without operator leases, " +
+				"When shards are pushed, " +
+				"Then every shard should be published to the local target and the target should stay healthy",
+			operators: []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}},
+			shards:    map[string]string{"shard-operator1-0": "operator1", "shard-operator2-0": "operator2"},
+			expectedShards: map[string][]string{
+				model.LocalShardTarget: {"shard-operator1-0", "shard-operator2-0"},
+				"us-east":              {},
+			},
+			expectedStatuses: map[string]bool{model.LocalShardTarget: true, "us-east": true},
+		},
+		{
+			name: "Given an operator which moved to another shard target, " +
+				"When its shard is pushed, " +
+				"Then the shard should be published to the new target and deleted from the previous one",
+			operators: []model.Operator{{Identity: "operator1", Target: "us-east"}},
+			existing:  map[string][]string{model.LocalShardTarget: {"shard-operator1-0"}},
+			shards:    map[string]string{"shard-operator1-0": "operator1"},
+			expectedShards: map[string][]string{
+				model.LocalShardTarget: {},
+				"us-east":              {"shard-operator1-0"},
+			},
+			expectedStatuses: map[string]bool{model.LocalShardTarget: true, "us-east": true},
+		},
+		{
+			name: "Given an unreachable shard target, " +
+				"When shards are pushed, " +
+				"Then the target should be reported unhealthy and other targets should still be published to",
+			operators:   []model.Operator{{Identity: "operator1"}, {Identity: "operator2", Target: "us-east"}},
+			unreachable: "us-east",
+			shards:      map[string]string{"shard-operator1-0": "operator1", "shard-operator2-0": "operator2"},
+			expectedShards: map[string][]string{
+				model.LocalShardTarget: {"shard-operator1-0"},
+				"us-east":              {},
+			},
+			expectedStatuses: map[string]bool{model.LocalShardTarget: true, "us-east": false},
+			expectedError:    true,
+		},
+		{
+			name: "Given an operator running in an unknown shard target, " +
+				"When its shard is pushed, " +
+				"Then there should be non nil error",
+			operators:        []model.Operator{{Identity: "operator1", Target: "unknown"}},
+			shards:           map[string]string{"shard-operator1-0": "operator1"},
+			expectedShards:   map[string][]string{model.LocalShardTarget: {}, "us-east": {}},
+			expectedStatuses: map[string]bool{model.LocalShardTarget: true, "us-east": true},
+			expectedError:    true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			targets := map[string]*fakeShardHandler{
+				model.LocalShardTarget: newFakeShardHandler(params),
+				"us-east":              newFakeShardHandler(params),
+			}
+			for target, shardNames := range c.existing {
+				for _, shardName := range shardNames {
+					targets[target].shards[shardName] = buildShardResource(clusters, params, shardName, "previous")
+				}
+			}
+			handlers := make(map[string]ShardInterface)
+			for name, target := range targets {
+				handlers[name] = target
+			}
+			multiClusterShardHandler := NewMultiClusterShardHandler(handlers, nil, params)
+			multiClusterShardHandler.RouteOperators(c.operators)
+			_, err := multiClusterShardHandler.List(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error while listing shards: %v", err)
+			}
+			if c.unreachable != "" {
+				targets[c.unreachable].err = fmt.Errorf("connection refused")
+			}
+
+			var pushErr error
+			for shardName, operatorIdentity := range c.shards {
+				_, err = multiClusterShardHandler.Create(ctx, clusters, shardName, operatorIdentity)
+				if k8sErrors.IsAlreadyExists(err) {
+					_, err = multiClusterShardHandler.Update(ctx, clusters, shardName, operatorIdentity)
+				}
+				if err != nil {
+					pushErr = err
+				}
+			}
+			if c.expectedError != (pushErr != nil) {
+				t.Errorf("expected error %v, got %v", c.expectedError, pushErr)
+			}
+			actualShards := make(map[string][]string)
+			for name, target := range targets {
+				actualShards[name] = target.getShardNames()
+			}
+			if !cmp.Equal(actualShards, c.expectedShards) {
+				t.Errorf(cmp.Diff(actualShards, c.expectedShards))
+			}
+			actualStatuses := make(map[string]bool)
+			for _, status := range multiClusterShardHandler.GetTargetStatus() {
+				actualStatuses[status.Name] = status.Healthy
+			}
+			if !cmp.Equal(actualStatuses, c.expectedStatuses) {
+				t.Errorf(cmp.Diff(actualStatuses, c.expectedStatuses))
+			}
+		})
+	}
+}
+
+func TestMultiClusterShardHandlerList(t *testing.T) {
+	params := &model.ShardingManagerParams{ShardNamespace: "shard-namespace"}
+	ctx := context.Background()
+	clusters := []registry.ClusterConfig{getTestClusterConfig("cluster1", 1)}
+
+	testCases := []struct {
+		name           string
+		listedBefore   bool
+		expectedShards []string
+		expectedError  bool
+	}{
+		{
+			name: "Given a shard target which became unreachable, " +
+				"When shards are listed, " +
+				"Then its last known shards should be returned along with shards of other targets",
+			listedBefore:   true,
+			expectedShards: []string{"shard-operator1-0", "shard-operator2-0"},
+		},
+		{
+			name: "Given a shard target which was never reachable, " +
+				"When shards are listed, " +
+				"Then there should be non nil error rather than its shards missing",
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			local, remote := newFakeShardHandler(params), newFakeShardHandler(params)
+			local.shards["shard-operator1-0"] = buildShardResource(clusters, params, "shard-operator1-0", "operator1")
+			remote.shards["shard-operator2-0"] = buildShardResource(clusters, params, "shard-operator2-0", "operator2")
+			multiClusterShardHandler := NewMultiClusterShardHandler(map[string]ShardInterface{model.LocalShardTarget: local, "us-east": remote}, nil, params)
+			if c.listedBefore {
+				_, err := multiClusterShardHandler.List(ctx)
+				if err != nil {
+					t.Fatalf("unexpected error while listing shards: %v", err)
+				}
+			}
+			remote.err = fmt.Errorf("connection refused")
+
+			shards, err := multiClusterShardHandler.List(ctx)
+			if c.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", c.expectedError, err)
+			}
+			var actualShards []string
+			for _, shard := range shards {
+				actualShards = append(actualShards, shard.Name)
+			}
+			sort.Strings(actualShards)
+			if !cmp.Equal(actualShards, c.expectedShards) {
+				t.Errorf(cmp.Diff(actualShards, c.expectedShards))
+			}
+		})
+	}
+}
+
+func TestMultiClusterShardHandlerLoadTargets(t *testing.T) {
+	params := &model.ShardingManagerParams{ShardNamespace: "shard-namespace"}
+	ctx := context.Background()
+	clusters := []registry.ClusterConfig{getTestClusterConfig("cluster1", 1)}
+	local := newFakeShardHandler(params)
+	loaded := map[string]ShardInterface{"us-east": newFakeShardHandler(params)}
+	var loadErr error
+	loader := func(ctx context.Context, smParams *model.ShardingManagerParams) (map[string]ShardInterface, error) {
+		return loaded, loadErr
+	}
+	multiClusterShardHandler := NewMultiClusterShardHandler(map[string]ShardInterface{model.LocalShardTarget: local}, loader, params)
+	multiClusterShardHandler.RouteOperators([]model.Operator{{Identity: "operator1", Target: "us-east"}, {Identity: "operator2", Target: "us-west"}})
+
+	getTargetNames := func() []string {
+		var names []string
+		for _, status := range multiClusterShardHandler.GetTargetStatus() {
+			names = append(names, status.Name)
+		}
+		return names
+	}
+
+	// a target added after start is picked up by the next sync
+	err := multiClusterShardHandler.LoadTargets(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading shard targets: %v", err)
+	}
+	if expected := []string{model.LocalShardTarget, "us-east"}; !cmp.Equal(getTargetNames(), expected) {
+		t.Errorf(cmp.Diff(getTargetNames(), expected))
+	}
+	_, err = multiClusterShardHandler.Create(ctx, clusters, "shard-operator1-0", "operator1")
+	if err != nil {
+		t.Errorf("unexpected error while creating shard on added shard target: %v", err)
+	}
+
+	// current targets are kept when targets cannot be resolved
+	loadErr = fmt.Errorf("connection refused")
+	err = multiClusterShardHandler.LoadTargets(ctx)
+	if err == nil {
+		t.Errorf("expected error while loading shard targets")
+	}
+	if expected := []string{model.LocalShardTarget, "us-east"}; !cmp.Equal(getTargetNames(), expected) {
+		t.Errorf(cmp.Diff(getTargetNames(), expected))
+	}
+
+	// a removed target is no longer published to while the local target is always kept
+	loadErr = nil
+	loaded = map[string]ShardInterface{"us-west": newFakeShardHandler(params)}
+	err = multiClusterShardHandler.LoadTargets(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while loading shard targets: %v", err)
+	}
+	if expected := []string{model.LocalShardTarget, "us-west"}; !cmp.Equal(getTargetNames(), expected) {
+		t.Errorf(cmp.Diff(getTargetNames(), expected))
+	}
+	_, err = multiClusterShardHandler.Create(ctx, clusters, "shard-operator1-1", "operator1")
+	if err == nil {
+		t.Errorf("expected error while creating shard on removed shard target")
+	}
+	_, err = multiClusterShardHandler.Create(ctx, clusters, "shard-operator2-0", "operator2")
+	if err != nil {
+		t.Errorf("unexpected error while creating shard on added shard target: %v", err)
+	}
+}
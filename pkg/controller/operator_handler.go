@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+
+	log "github.com/sirupsen/logrus"
+	coordinationV1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Interface to discover admiral operators
+type OperatorInterface interface {
+	// list admiral operators which maintain a heartbeat lease in the shard namespace of the local cluster
+	List(ctx context.Context) ([]model.Operator, error)
+	// mark operator as cordoned or draining, an empty state makes the operator schedulable again
+	SetSchedulingState(ctx context.Context, operatorIdentity string, state string) error
+}
+
+type operatorHandler struct {
+	clients model.Clients
+	*handlerParams
+}
+
+// initializes OperatorHandler with sharding manager configuration
+// admiral operators are discovered through leases labelled with the operator identity label
+func NewOperatorHandler(clients model.Clients, smParams *model.ShardingManagerParams) *operatorHandler {
+	return &operatorHandler{
+		clients:       clients,
+		handlerParams: newHandlerParams(smParams),
+	}
+}
+
+func (oh *operatorHandler) List(ctx context.Context) ([]model.Operator, error) {
+	var operators []model.Operator
+	leases, err := oh.clients.KubernetesClient.CoordinationV1().Leases(oh.params().ShardNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: oh.params().OperatorIdentityLabel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operator leases: %v", err)
+	}
+	for _, lease := range leases.Items {
+		operators = append(operators, buildOperator(lease, oh.params()))
+	}
+	return operators, nil
+}
+
+func (oh *operatorHandler) SetSchedulingState(ctx context.Context, operatorIdentity string, state string) error {
+	leases, err := oh.clients.KubernetesClient.CoordinationV1().Leases(oh.params().ShardNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: oh.params().OperatorIdentityLabel + "=" + operatorIdentity,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list leases of operator %s: %v", operatorIdentity, err)
+	}
+	if len(leases.Items) == 0 {
+		return fmt.Errorf("operator %s is not discovered in namespace %s", operatorIdentity, oh.params().ShardNamespace)
+	}
+	value := "null"
+	if state != "" {
+		value = strconv.Quote(state)
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%s}}}`, model.OperatorSchedulingStateAnnotation, value)
+	for _, lease := range leases.Items {
+		_, err = oh.clients.KubernetesClient.CoordinationV1().Leases(lease.Namespace).Patch(ctx, lease.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update scheduling state of operator lease %s: %v", lease.Name, err)
+		}
+	}
+	return nil
+}
+
+func buildOperator(lease coordinationV1.Lease, smParams *model.ShardingManagerParams) model.Operator {
+	operator := model.Operator{
+		Identity:        lease.Labels[smParams.OperatorIdentityLabel],
+		Namespace:       lease.Namespace,
+		LeaseName:       lease.Name,
+		Labels:          lease.Labels,
+		Annotations:     lease.Annotations,
+		Capacity:        getOperatorCapacity(lease, smParams.OperatorCapacityKey),
+		SchedulingState: lease.Annotations[model.OperatorSchedulingStateAnnotation],
+		Locality:        lease.Labels[smParams.OperatorLocalityLabel],
+		Segment:         lease.Labels[smParams.OperatorSegmentLabel],
+		Pool:            lease.Labels[smParams.OperatorPoolLabel],
+		Target:          lease.Labels[smParams.OperatorTargetLabel],
+	}
+	if lease.Spec.RenewTime != nil {
+		operator.LastHeartbeat = lease.Spec.RenewTime.Time
+	} else if lease.Spec.AcquireTime != nil {
+		operator.LastHeartbeat = lease.Spec.AcquireTime.Time
+	}
+	if lease.Spec.LeaseDurationSeconds != nil {
+		operator.LeaseDuration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return operator
+}
+
+// reads capacity weight declared by the operator, annotation takes precedence over label
+// operators which do not declare a valid capacity, a positive finite number, are assigned the default capacity
+func getOperatorCapacity(lease coordinationV1.Lease, capacityKey string) float64 {
+	value, ok := lease.Annotations[capacityKey]
+	if !ok {
+		value, ok = lease.Labels[capacityKey]
+	}
+	if !ok || capacityKey == "" {
+		return model.DefaultOperatorCapacity
+	}
+	capacity, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(capacity) || math.IsInf(capacity, 0) || capacity <= 0 {
+		log.Warnf("invalid capacity %q declared by operator lease %s/%s, using default capacity", value, lease.Namespace, lease.Name)
+		return model.DefaultOperatorCapacity
+	}
+	return capacity
+}
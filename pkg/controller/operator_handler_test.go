@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	coordinationV1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testCapacityKey = "admiral.io/operatorCapacity"
+
+func getTestLease(name string, labels map[string]string, annotations map[string]string) *coordinationV1.Lease {
+	return &coordinationV1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "shard-namespace",
+			Labels:      labels,
+			Annotations: annotations,
+		},
+	}
+}
+
+func TestGetOperatorCapacity(t *testing.T) {
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    float64
+	}{
+		{
+			name: "Given an operator which does not declare its capacity, " +
+				"When its capacity is read, " +
+				"Then the default capacity should be returned",
+			expected: model.DefaultOperatorCapacity,
+		},
+		{
+			name: "Given an operator declaring its capacity as a label, " +
+				"When its capacity is read, " +
+				"Then the declared capacity should be returned",
+			labels:   map[string]string{testCapacityKey: "2.5"},
+			expected: 2.5,
+		},
+		{
+			name: "Given an operator declaring its capacity as a label and an annotation, " +
+				"When its capacity is read, " +
+				"Then the annotation should take precedence",
+			labels:      map[string]string{testCapacityKey: "2"},
+			annotations: map[string]string{testCapacityKey: "3"},
+			expected:    3,
+		},
+		{
+			name: "Given an operator declaring a capacity which is not a number, " +
+				"When its capacity is read, " +
+				"Then the default capacity should be returned",
+			annotations: map[string]string{testCapacityKey: "large"},
+			expected:    model.DefaultOperatorCapacity,
+		},
+		{
+			name: "Given an operator declaring a negative capacity, " +
+				"When its capacity is read, " +
+				"Then the default capacity should be returned",
+			annotations: map[string]string{testCapacityKey: "-1"},
+			expected:    model.DefaultOperatorCapacity,
+		},
+		{
+			name: "Given an operator declaring a NaN capacity, " +
+				"When its capacity is read, " +
+				"Then the default capacity should be returned",
+			annotations: map[string]string{testCapacityKey: "NaN"},
+			expected:    model.DefaultOperatorCapacity,
+		},
+		{
+			name: "Given an operator declaring an infinite capacity, " +
+				"When its capacity is read, " +
+				"Then the default capacity should be returned",
+			annotations: map[string]string{testCapacityKey: "+Inf"},
+			expected:    model.DefaultOperatorCapacity,
+		},
+		{
+			name: "Given an operator declaring a capacity out of range, " +
+				"When its capacity is read, " +
+				"Then the default capacity should be returned",
+			annotations: map[string]string{testCapacityKey: "1e309"},
+			expected:    model.DefaultOperatorCapacity,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := getOperatorCapacity(*getTestLease("operator1", c.labels, c.annotations), testCapacityKey)
+			if actual != c.expected {
+				t.Errorf("expected capacity %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBuildOperator(t *testing.T) {
+	params := &model.ShardingManagerParams{
+		OperatorIdentityLabel: "admiral.io/operatorIdentity",
+		OperatorCapacityKey:   testCapacityKey,
+	}
+	renewTime := metav1.NewMicroTime(time.Date(2024, 6, 21, 18, 25, 0, 0, time.UTC))
+	acquireTime := metav1.NewMicroTime(time.Date(2024, 6, 21, 18, 0, 0, 0, time.UTC))
+	leaseDuration := int32(10)
+	testCases := []struct {
+		name                  string
+		spec                  coordinationV1.LeaseSpec
+		annotations           map[string]string
+		expectedHeartbeat     time.Time
+		expectedLeaseDuration time.Duration
+		expectedState         string
+	}{
+		{
+			name: "Given a renewed lease, " +
+				"When the operator is built, " +
+				"Then its heartbeat should be the renew time",
+			spec:                  coordinationV1.LeaseSpec{AcquireTime: &acquireTime, RenewTime: &renewTime, LeaseDurationSeconds: &leaseDuration},
+			expectedHeartbeat:     renewTime.Time,
+			expectedLeaseDuration: 10 * time.Second,
+		},
+		{
+			name: "Given a lease which was acquired but not renewed, " +
+				"When the operator is built, " +
+				"Then its heartbeat should be the acquire time",
+			spec:              coordinationV1.LeaseSpec{AcquireTime: &acquireTime},
+			expectedHeartbeat: acquireTime.Time,
+		},
+		{
+			name: "Given a lease without heartbeat, " +
+				"When the operator is built, " +
+				"Then it should have no heartbeat",
+		},
+		{
+			name: "Given a lease of a draining operator, " +
+				"When the operator is built, " +
+				"Then its scheduling state should be draining",
+			annotations:   map[string]string{model.OperatorSchedulingStateAnnotation: model.DrainingSchedulingState},
+			expectedState: model.DrainingSchedulingState,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			lease := getTestLease("operator1-lease", map[string]string{params.OperatorIdentityLabel: "operator1"}, c.annotations)
+			lease.Spec = c.spec
+			operator := buildOperator(*lease, params)
+			if operator.Identity != "operator1" || operator.LeaseName != "operator1-lease" || operator.Namespace != "shard-namespace" {
+				t.Errorf("unexpected operator identity %q, lease %s/%s", operator.Identity, operator.Namespace, operator.LeaseName)
+			}
+			if !operator.LastHeartbeat.Equal(c.expectedHeartbeat) {
+				t.Errorf("expected heartbeat %s, got %s", c.expectedHeartbeat, operator.LastHeartbeat)
+			}
+			if operator.LeaseDuration != c.expectedLeaseDuration {
+				t.Errorf("expected lease duration %s, got %s", c.expectedLeaseDuration, operator.LeaseDuration)
+			}
+			if operator.SchedulingState != c.expectedState {
+				t.Errorf("expected scheduling state %q, got %q", c.expectedState, operator.SchedulingState)
+			}
+		})
+	}
+}
+
+func TestOperatorHandler(t *testing.T) {
+	ctx := context.Background()
+	params := &model.ShardingManagerParams{
+		ShardNamespace:        "shard-namespace",
+		OperatorIdentityLabel: "admiral.io/operatorIdentity",
+	}
+	client := fake.NewSimpleClientset(
+		getTestLease("operator1", map[string]string{params.OperatorIdentityLabel: "operator1"}, nil),
+		getTestLease("operator2", map[string]string{params.OperatorIdentityLabel: "operator2"}, nil),
+		getTestLease("unrelated", map[string]string{"app": "unrelated"}, nil),
+	)
+	handler := NewOperatorHandler(model.Clients{KubernetesClient: client}, params)
+
+	err := handler.SetSchedulingState(ctx, "operator1", model.CordonedSchedulingState)
+	if err != nil {
+		t.Fatalf("failed to cordon operator: %v", err)
+	}
+	err = handler.SetSchedulingState(ctx, "operator3", model.CordonedSchedulingState)
+	if err == nil || err.Error() != "operator operator3 is not discovered in namespace shard-namespace" {
+		t.Errorf("expected undiscovered operator error, got %v", err)
+	}
+	operators, err := handler.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list operators: %v", err)
+	}
+	actual := make(map[string]string)
+	for _, operator := range operators {
+		actual[operator.Identity] = operator.SchedulingState
+	}
+	expected := map[string]string{"operator1": model.CordonedSchedulingState, "operator2": ""}
+	if !cmp.Equal(actual, expected) {
+		t.Errorf(cmp.Diff(actual, expected))
+	}
+
+	err = handler.SetSchedulingState(ctx, "operator1", "")
+	if err != nil {
+		t.Fatalf("failed to uncordon operator: %v", err)
+	}
+	lease, err := client.CoordinationV1().Leases("shard-namespace").Get(ctx, "operator1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if _, ok := lease.Annotations[model.OperatorSchedulingStateAnnotation]; ok {
+		t.Errorf("expected scheduling state annotation to be removed, got %v", lease.Annotations)
+	}
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Interface to load placement overrides
+type OverrideInterface interface {
+	// fetch placement overrides defined in the shard namespace
+	List(ctx context.Context) ([]model.PlacementOverride, error)
+	// reference to the object holding placement overrides, used to record events
+	Reference() *coreV1.ObjectReference
+}
+
+type overrideHandler struct {
+	clients model.Clients
+	*handlerParams
+}
+
+// initializes OverrideHandler with sharding manager configuration
+// placement overrides are read from a configmap in the shard namespace
+func NewOverrideHandler(clients model.Clients, smParams *model.ShardingManagerParams) *overrideHandler {
+	return &overrideHandler{
+		clients:       clients,
+		handlerParams: newHandlerParams(smParams),
+	}
+}
+
+func (oh *overrideHandler) List(ctx context.Context) ([]model.PlacementOverride, error) {
+	configMap, err := oh.clients.KubernetesClient.CoreV1().ConfigMaps(oh.params().ShardNamespace).Get(ctx, oh.params().OverridesConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overrides configmap: %v", err)
+	}
+	return parsePlacementOverrides(configMap.Data[model.OverridesConfigMapKey])
+}
+
+func (oh *overrideHandler) Reference() *coreV1.ObjectReference {
+	return &coreV1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  oh.params().ShardNamespace,
+		Name:       oh.params().OverridesConfigMap,
+	}
+}
+
+// parses placement overrides defined as yaml or json
+func parsePlacementOverrides(data string) ([]model.PlacementOverride, error) {
+	var overrides model.PlacementOverrides
+	err := yaml.UnmarshalStrict([]byte(data), &overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse placement overrides: %v", err)
+	}
+	return overrides.Overrides, nil
+}
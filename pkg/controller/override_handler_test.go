@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOverrideHandlerList(t *testing.T) {
+	params := &model.ShardingManagerParams{
+		ShardNamespace:     "shard-namespace",
+		OverridesConfigMap: "admiral-sharding-overrides",
+	}
+	testCases := []struct {
+		name              string
+		data              *string
+		expectedOverrides []model.PlacementOverride
+		expectedError     string
+	}{
+		{
+			name: "Given no overrides configmap, " +
+				"When placement overrides are listed, " +
+				"Then no override should be returned",
+		},
+		{
+			name: "Given overrides defined as yaml, " +
+				"When placement overrides are listed, " +
+				"Then every override should be returned in order",
+			data: ptr("overrides:\n" +
+				"- cluster: cluster1\n  operator: operator1\n" +
+				"- identity: identity1\n  exclude: true\n" +
+				"- clusterSelector: segment=payments\n  operator: operator2\n"),
+			expectedOverrides: []model.PlacementOverride{
+				{Cluster: "cluster1", Operator: "operator1"},
+				{Identity: "identity1", Exclude: true},
+				{ClusterSelector: "segment=payments", Operator: "operator2"},
+			},
+		},
+		{
+			name: "Given overrides defined as json, " +
+				"When placement overrides are listed, " +
+				"Then every override should be returned",
+			data:              ptr(`{"overrides": [{"cluster": "cluster1", "exclude": true}]}`),
+			expectedOverrides: []model.PlacementOverride{{Cluster: "cluster1", Exclude: true}},
+		},
+		{
+			name: "Given malformed json, " +
+				"When placement overrides are listed, " +
+				"Then an error should be returned",
+			data:          ptr(`{"overrides": [{"cluster": "cluster1",`),
+			expectedError: "failed to parse placement overrides: ",
+		},
+		{
+			name: "Given an override with an unknown field, " +
+				"When placement overrides are listed, " +
+				"Then an error should be returned",
+			data:          ptr(`{"overrides": [{"clusterName": "cluster1", "operator": "operator1"}]}`),
+			expectedError: "failed to parse placement overrides: ",
+		},
+		{
+			// conflicting fields and operators are checked against the registry configuration and discovered
+			// operators on every sync, where such overrides are rejected with a reason
+			name: "Given overrides setting both a selector and a cluster, both exclude and an operator, or an unknown operator, " +
+				"When placement overrides are listed, " +
+				"Then they should be returned as defined",
+			data: ptr(`{"overrides": [` +
+				`{"cluster": "cluster1", "clusterSelector": "segment=payments", "operator": "operator1"},` +
+				`{"cluster": "cluster2", "operator": "operator1", "exclude": true},` +
+				`{"cluster": "cluster3", "operator": "unknown"}]}`),
+			expectedOverrides: []model.PlacementOverride{
+				{Cluster: "cluster1", ClusterSelector: "segment=payments", Operator: "operator1"},
+				{Cluster: "cluster2", Operator: "operator1", Exclude: true},
+				{Cluster: "cluster3", Operator: "unknown"},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if c.data != nil {
+				_, err := client.CoreV1().ConfigMaps(params.ShardNamespace).Create(context.Background(), &coreV1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: params.OverridesConfigMap, Namespace: params.ShardNamespace},
+					Data:       map[string]string{model.OverridesConfigMapKey: *c.data},
+				}, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("failed to create overrides configmap: %v", err)
+				}
+			}
+			overrides, err := NewOverrideHandler(model.Clients{KubernetesClient: client}, params).List(context.Background())
+			var actualError string
+			if err != nil {
+				actualError = err.Error()
+			}
+			if !strings.HasPrefix(actualError, c.expectedError) || (c.expectedError == "") != (actualError == "") {
+				t.Errorf("expected error starting with %q, got %q", c.expectedError, actualError)
+			}
+			if !cmp.Equal(overrides, c.expectedOverrides) {
+				t.Errorf(cmp.Diff(overrides, c.expectedOverrides))
+			}
+		})
+	}
+}
+
+func ptr(value string) *string {
+	return &value
+}
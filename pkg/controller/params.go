@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"sync/atomic"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+)
+
+// Interface of handlers reading settings which can be changed by a configuration reload
+type ParamsUpdater interface {
+	// replaces the settings of the handler, the provided settings must not be modified afterwards
+	UpdateParams(smParams *model.ShardingManagerParams)
+}
+
+// settings of a handler, replaced as a whole on a configuration reload so that requests in flight keep
+// reading the settings they started with
+type handlerParams struct {
+	current atomic.Pointer[model.ShardingManagerParams]
+}
+
+func newHandlerParams(smParams *model.ShardingManagerParams) *handlerParams {
+	hp := &handlerParams{}
+	hp.current.Store(smParams)
+	return hp
+}
+
+func (hp *handlerParams) params() *model.ShardingManagerParams {
+	return hp.current.Load()
+}
+
+func (hp *handlerParams) UpdateParams(smParams *model.ShardingManagerParams) {
+	hp.current.Store(smParams)
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// revisions are removed from the history beyond this size, leaving room for the metadata of the configmap
+// below the 1 MiB limit of objects
+var maxRevisionHistorySize = 900 * 1024
+
+// Interface to keep the history of assignment revisions
+type RevisionInterface interface {
+	// revisions kept in the history, oldest first, along with the revision the assignment is frozen at
+	Get(ctx context.Context) (model.RevisionHistory, error)
+	// appends a revision to the history and removes the oldest revisions beyond the limit or the history size
+	Record(ctx context.Context, revision model.AssignmentRevision, limit int) error
+	// freezes the assignment at a revision of the history, rebalancing resumes when the revision is 0
+	Freeze(ctx context.Context, revision int) error
+	// reference to the object holding the history, used to record events
+	Reference() *coreV1.ObjectReference
+}
+
+type revisionHandler struct {
+	clients model.Clients
+	*handlerParams
+}
+
+// initializes RevisionHandler with sharding manager configuration
+// revisions are kept in a configmap in the shard namespace, one key per revision
+func NewRevisionHandler(clients model.Clients, smParams *model.ShardingManagerParams) *revisionHandler {
+	return &revisionHandler{
+		clients:       clients,
+		handlerParams: newHandlerParams(smParams),
+	}
+}
+
+func (rh *revisionHandler) Get(ctx context.Context) (model.RevisionHistory, error) {
+	history := model.RevisionHistory{Revisions: []model.AssignmentRevision{}}
+	configMap, err := rh.clients.KubernetesClient.CoreV1().ConfigMaps(rh.params().ShardNamespace).Get(ctx, rh.params().RevisionHistoryConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return history, nil
+	}
+	if err != nil {
+		return history, fmt.Errorf("failed to get revision history configmap: %v", err)
+	}
+	return parseRevisionHistory(configMap)
+}
+
+func (rh *revisionHandler) Record(ctx context.Context, revision model.AssignmentRevision, limit int) error {
+	data, err := json.Marshal(revision)
+	if err != nil {
+		return fmt.Errorf("failed to marshal revision %d: %v", revision.Revision, err)
+	}
+	configMaps := rh.clients.KubernetesClient.CoreV1().ConfigMaps(rh.params().ShardNamespace)
+	configMap, err := configMaps.Get(ctx, rh.params().RevisionHistoryConfigMap, metav1.GetOptions{})
+	exists := err == nil
+	if errors.IsNotFound(err) {
+		configMap = &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      rh.params().RevisionHistoryConfigMap,
+				Namespace: rh.params().ShardNamespace,
+				Labels:    map[string]string{ShardIdentity: rh.params().ShardingManagerIdentity},
+			},
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to get revision history configmap: %v", err)
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[getRevisionKey(revision.Revision)] = string(data)
+	var revisions []int
+	for key := range configMap.Data {
+		if number, ok := parseRevisionKey(key); ok {
+			revisions = append(revisions, number)
+		}
+	}
+	sort.Ints(revisions)
+	// configmaps cannot exceed 1 MiB, the oldest revisions are removed as well until the history fits
+	for len(revisions) > limit || (len(revisions) > 1 && getConfigMapDataSize(configMap) > maxRevisionHistorySize) {
+		delete(configMap.Data, getRevisionKey(revisions[0]))
+		revisions = revisions[1:]
+	}
+	if size := getConfigMapDataSize(configMap); size > maxRevisionHistorySize {
+		return fmt.Errorf("revision %d of %d bytes exceeds the revision history size limit of %d bytes", revision.Revision, size, maxRevisionHistorySize)
+	}
+	if !exists {
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create revision history configmap: %v", err)
+		}
+		return nil
+	}
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update revision history configmap: %v", err)
+	}
+	return nil
+}
+
+func (rh *revisionHandler) Freeze(ctx context.Context, revision int) error {
+	configMaps := rh.clients.KubernetesClient.CoreV1().ConfigMaps(rh.params().ShardNamespace)
+	configMap, err := configMaps.Get(ctx, rh.params().RevisionHistoryConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) && revision == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get revision history configmap: %v", err)
+	}
+	value := "null"
+	if revision != 0 {
+		if _, ok := configMap.Data[getRevisionKey(revision)]; !ok {
+			return fmt.Errorf("revision %d is not part of the revision history", revision)
+		}
+		value = strconv.Quote(strconv.Itoa(revision))
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%s}}}`, model.FrozenRevisionAnnotation, value)
+	_, err = configMaps.Patch(ctx, configMap.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update frozen revision of revision history configmap: %v", err)
+	}
+	return nil
+}
+
+func (rh *revisionHandler) Reference() *coreV1.ObjectReference {
+	return &coreV1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  rh.params().ShardNamespace,
+		Name:       rh.params().RevisionHistoryConfigMap,
+	}
+}
+
+// size of the keys and values of a configmap
+func getConfigMapDataSize(configMap *coreV1.ConfigMap) int {
+	var size int
+	for key, value := range configMap.Data {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+func getRevisionKey(revision int) string {
+	return model.RevisionKeyPrefix + strconv.Itoa(revision)
+}
+
+func parseRevisionKey(key string) (int, bool) {
+	if !strings.HasPrefix(key, model.RevisionKeyPrefix) {
+		return 0, false
+	}
+	revision, err := strconv.Atoi(strings.TrimPrefix(key, model.RevisionKeyPrefix))
+	return revision, err == nil
+}
+
+func parseRevisionHistory(configMap *coreV1.ConfigMap) (model.RevisionHistory, error) {
+	history := model.RevisionHistory{Revisions: []model.AssignmentRevision{}}
+	for key, data := range configMap.Data {
+		if _, ok := parseRevisionKey(key); !ok {
+			continue
+		}
+		var revision model.AssignmentRevision
+		err := json.Unmarshal([]byte(data), &revision)
+		if err != nil {
+			return history, fmt.Errorf("failed to parse %s of revision history configmap: %v", key, err)
+		}
+		history.Revisions = append(history.Revisions, revision)
+	}
+	sort.Slice(history.Revisions, func(i, j int) bool {
+		return history.Revisions[i].Revision < history.Revisions[j].Revision
+	})
+	if value, ok := configMap.Annotations[model.FrozenRevisionAnnotation]; ok {
+		revision, err := strconv.Atoi(value)
+		if err != nil {
+			return history, fmt.Errorf("invalid frozen revision %q of revision history configmap: %v", value, err)
+		}
+		history.FrozenRevision = revision
+	}
+	return history, nil
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func getTestRevision(revision int) model.AssignmentRevision {
+	return model.AssignmentRevision{
+		Revision:  revision,
+		Time:      time.Date(2024, 6, 21, 18, revision, 0, 0, time.UTC),
+		Operators: map[string][]string{"operator1": {"cluster1"}},
+	}
+}
+
+func TestRevisionHandler(t *testing.T) {
+	params := &model.ShardingManagerParams{
+		ShardNamespace:           "shard-namespace",
+		RevisionHistoryConfigMap: "admiral-sharding-revisions",
+	}
+	testCases := []struct {
+		name              string
+		recorded          []int
+		limit             int
+		freeze            int
+		expectedRevisions []int
+		expectedFrozen    int
+		expectedError     string
+	}{
+		{
+			name: "Given no revision history, " +
+				"When the history is read, " +
+				"Then it should be empty",
+			expectedRevisions: []int{},
+		},
+		{
+			name: "Given more revisions recorded than the limit, " +
+				"When the history is read, " +
+				"Then only the latest revisions should be kept",
+			recorded:          []int{1, 2, 3, 4},
+			limit:             2,
+			expectedRevisions: []int{3, 4},
+		},
+		{
+			name: "Given a revision of the history, " +
+				"When the assignment is frozen at it, " +
+				"Then the history should report the frozen revision",
+			recorded:          []int{1, 2},
+			limit:             10,
+			freeze:            1,
+			expectedRevisions: []int{1, 2},
+			expectedFrozen:    1,
+		},
+		{
+			name: "Given a revision which is no longer part of the history, " +
+				"When the assignment is frozen at it, " +
+				"Then an error should be returned",
+			recorded:          []int{1, 2, 3},
+			limit:             2,
+			freeze:            1,
+			expectedRevisions: []int{2, 3},
+			expectedError:     "revision 1 is not part of the revision history",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			handler := NewRevisionHandler(model.Clients{KubernetesClient: fake.NewSimpleClientset()}, params)
+			for _, revision := range c.recorded {
+				err := handler.Record(ctx, getTestRevision(revision), c.limit)
+				if err != nil {
+					t.Fatalf("failed to record revision %d: %v", revision, err)
+				}
+			}
+			var actualError string
+			if c.freeze != 0 {
+				err := handler.Freeze(ctx, c.freeze)
+				if err != nil {
+					actualError = err.Error()
+				}
+			}
+			if actualError != c.expectedError {
+				t.Errorf(cmp.Diff(actualError, c.expectedError))
+			}
+			history, err := handler.Get(ctx)
+			if err != nil {
+				t.Fatalf("failed to get revision history: %v", err)
+			}
+			actualRevisions := []int{}
+			for _, revision := range history.Revisions {
+				actualRevisions = append(actualRevisions, revision.Revision)
+				if !cmp.Equal(revision, getTestRevision(revision.Revision)) {
+					t.Errorf(cmp.Diff(revision, getTestRevision(revision.Revision)))
+				}
+			}
+			if !cmp.Equal(actualRevisions, c.expectedRevisions) {
+				t.Errorf(cmp.Diff(actualRevisions, c.expectedRevisions))
+			}
+			if history.FrozenRevision != c.expectedFrozen {
+				t.Errorf("expected frozen revision %d, got %d", c.expectedFrozen, history.FrozenRevision)
+			}
+		})
+	}
+}
+
+func TestRevisionHandlerResume(t *testing.T) {
+	ctx := context.Background()
+	handler := NewRevisionHandler(model.Clients{KubernetesClient: fake.NewSimpleClientset()}, &model.ShardingManagerParams{
+		ShardNamespace:           "shard-namespace",
+		RevisionHistoryConfigMap: "admiral-sharding-revisions",
+	})
+	err := handler.Record(ctx, getTestRevision(1), 10)
+	if err != nil {
+		t.Fatalf("failed to record revision: %v", err)
+	}
+	for _, revision := range []int{1, 0} {
+		err = handler.Freeze(ctx, revision)
+		if err != nil {
+			t.Fatalf("failed to freeze at revision %d: %v", revision, err)
+		}
+	}
+	history, err := handler.Get(ctx)
+	if err != nil {
+		t.Fatalf("failed to get revision history: %v", err)
+	}
+	if history.FrozenRevision != 0 {
+		t.Errorf("expected rebalancing to be resumed, got frozen revision %d", history.FrozenRevision)
+	}
+}
+
+func TestRevisionHandlerSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	handler := NewRevisionHandler(model.Clients{KubernetesClient: fake.NewSimpleClientset()}, &model.ShardingManagerParams{
+		ShardNamespace:           "shard-namespace",
+		RevisionHistoryConfigMap: "admiral-sharding-revisions",
+	})
+	// the history fits the two latest revisions only
+	var size int
+	for _, revision := range []int{3, 4} {
+		data, err := json.Marshal(getTestRevision(revision))
+		if err != nil {
+			t.Fatalf("failed to marshal revision: %v", err)
+		}
+		size += len(getRevisionKey(revision)) + len(data)
+	}
+	previousSize := maxRevisionHistorySize
+	maxRevisionHistorySize = size
+	t.Cleanup(func() {
+		maxRevisionHistorySize = previousSize
+	})
+
+	for _, revision := range []int{1, 2, 3, 4} {
+		err := handler.Record(ctx, getTestRevision(revision), 10)
+		if err != nil {
+			t.Fatalf("failed to record revision %d: %v", revision, err)
+		}
+	}
+	history, err := handler.Get(ctx)
+	if err != nil {
+		t.Fatalf("failed to get revision history: %v", err)
+	}
+	actualRevisions := []int{}
+	for _, revision := range history.Revisions {
+		actualRevisions = append(actualRevisions, revision.Revision)
+	}
+	if !cmp.Equal(actualRevisions, []int{3, 4}) {
+		t.Errorf(cmp.Diff(actualRevisions, []int{3, 4}))
+	}
+
+	// a revision which does not fit on its own is not recorded
+	large := getTestRevision(5)
+	large.Operators["operator2"] = []string{strings.Repeat("cluster", size)}
+	err = handler.Record(ctx, large, 10)
+	if err == nil || !strings.HasPrefix(err.Error(), "revision 5 of ") {
+		t.Errorf("expected revision exceeding the size limit to be rejected, got %v", err)
+	}
+	history, err = handler.Get(ctx)
+	if err != nil {
+		t.Fatalf("failed to get revision history: %v", err)
+	}
+	if len(history.Revisions) != 2 {
+		t.Errorf("expected history to be left untouched, got %d revisions", len(history.Revisions))
+	}
+}
@@ -2,17 +2,21 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
 
+	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	ShardIdentity = "admiral.io/shardIdentity"
+	ShardKind     = "Shard"
 )
 
 // Interface to manage shards
@@ -23,18 +27,22 @@ type ShardInterface interface {
 	Update(ctx context.Context, clusterConfiguration []registry.ClusterConfig, shardName string, operatorIdentity string) (*typeV1.Shard, error)
 	// delete shard resource on a kubernetes cluster
 	Delete(ctx context.Context, shard *typeV1.Shard) error
+	// list shard resources managed by this sharding manager instance
+	List(ctx context.Context) ([]typeV1.Shard, error)
+	// split cluster configuration of an operator into partitions, each of which fits in a single shard
+	Partition(clusterConfiguration []registry.ClusterConfig, operatorIdentity string) [][]registry.ClusterConfig
 }
 
 type shardHandler struct {
 	clients model.Clients
-	params  *model.ShardingManagerParams
+	*handlerParams
 }
 
 // initializes ShardHandler with sharding manager configuration and shard namespace
 func NewShardHandler(clients model.Clients, smParams *model.ShardingManagerParams) *shardHandler {
 	shardHandler := &shardHandler{
-		clients: clients,
-		params:  smParams,
+		clients:       clients,
+		handlerParams: newHandlerParams(smParams),
 	}
 	return shardHandler
 }
@@ -44,8 +52,8 @@ func (sh *shardHandler) Create(
 	clusterConfiguration []registry.ClusterConfig,
 	shardName string,
 	operatorIdentity string) (*typeV1.Shard, error) {
-	shardToCreate := buildShardResource(clusterConfiguration, sh.params, shardName, operatorIdentity)
-	return sh.clients.AdmiralClient.Shards(sh.params.ShardNamespace).Create(ctx, shardToCreate, metav1.CreateOptions{})
+	shardToCreate := buildShardResource(clusterConfiguration, sh.params(), shardName, operatorIdentity)
+	return sh.clients.AdmiralClient.Shards(sh.params().ShardNamespace).Create(ctx, shardToCreate, metav1.CreateOptions{})
 }
 
 func (sh *shardHandler) Update(
@@ -54,15 +62,15 @@ func (sh *shardHandler) Update(
 	shardName string,
 	operatorIdentity string) (*typeV1.Shard, error) {
 	var updatedShard *typeV1.Shard
-	existingShard, err := sh.clients.AdmiralClient.Shards(sh.params.ShardNamespace).Get(ctx, shardName, metav1.GetOptions{})
-	shardToUpdate := buildShardResource(clusterConfiguration, sh.params, shardName, operatorIdentity)
+	existingShard, err := sh.clients.AdmiralClient.Shards(sh.params().ShardNamespace).Get(ctx, shardName, metav1.GetOptions{})
+	shardToUpdate := buildShardResource(clusterConfiguration, sh.params(), shardName, operatorIdentity)
 
 	if existingShard != nil && shardToUpdate != nil {
 		existingShard.Labels = shardToUpdate.Labels
 		existingShard.Annotations = shardToUpdate.Annotations
 		existingShard.Spec = shardToUpdate.Spec
 
-		updatedShard, err = sh.clients.AdmiralClient.Shards(sh.params.ShardNamespace).Update(ctx, existingShard, metav1.UpdateOptions{})
+		updatedShard, err = sh.clients.AdmiralClient.Shards(sh.params().ShardNamespace).Update(ctx, existingShard, metav1.UpdateOptions{})
 		if err != nil {
 			return nil, fmt.Errorf("failed to update shard resource: %v", err)
 		}
@@ -71,13 +79,165 @@ func (sh *shardHandler) Update(
 }
 
 func (sh *shardHandler) Delete(ctx context.Context, shard *typeV1.Shard) error {
-	err := sh.clients.AdmiralClient.Shards(sh.params.ShardNamespace).Delete(ctx, shard.Name, metav1.DeleteOptions{})
+	err := sh.clients.AdmiralClient.Shards(sh.params().ShardNamespace).Delete(ctx, shard.Name, metav1.DeleteOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete shard resource: %v", err)
 	}
 	return err
 }
 
+func (sh *shardHandler) List(ctx context.Context) ([]typeV1.Shard, error) {
+	shards, err := sh.clients.AdmiralClient.Shards(sh.params().ShardNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: ShardIdentity + "=" + sh.params().ShardingManagerIdentity,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shard resources: %v", err)
+	}
+	return shards.Items, nil
+}
+
+func (sh *shardHandler) Partition(clusterConfiguration []registry.ClusterConfig, operatorIdentity string) [][]registry.ClusterConfig {
+	return partitionClusterConfigs(clusterConfiguration, sh.params(), operatorIdentity)
+}
+
+// builds shard resources for every operator in the assignment, an operator's clusters are split across
+// multiple shards when they exceed the configured shard size limits
+func BuildShardResources(assignment model.ShardAssignment, smParam *model.ShardingManagerParams) []*typeV1.Shard {
+	var (
+		shards     []*typeV1.Shard
+		identities []string
+	)
+	for operatorIdentity := range assignment {
+		identities = append(identities, operatorIdentity)
+	}
+	sort.Strings(identities)
+	for _, operatorIdentity := range identities {
+		for index, clusters := range partitionClusterConfigs(assignment[operatorIdentity], smParam, operatorIdentity) {
+			shards = append(shards, buildShardResource(clusters, smParam, GetShardName(operatorIdentity, index), operatorIdentity))
+		}
+	}
+	return shards
+}
+
+// returns name of the shard at provided index of operator's shards
+func GetShardName(operatorIdentity string, index int) string {
+	return fmt.Sprintf("%s-%s-%d", model.ShardNamePrefix, operatorIdentity, index)
+}
+
+// splits cluster configuration into partitions which do not exceed the configured number of clusters,
+// number of identities and serialized size of a shard. A cluster which exceeds the limits on its own
+// is placed in a dedicated partition as clusters are never split across shards
+func partitionClusterConfigs(clusterConfigs []registry.ClusterConfig, smParam *model.ShardingManagerParams, operatorIdentity string) [][]registry.ClusterConfig {
+	clusterConfigs = sortClusterConfigs(mergeClusterConfigs(clusterConfigs))
+	var (
+		partitions [][]registry.ClusterConfig
+		partition  []registry.ClusterConfig
+		identities int
+		// an upper bound for the size of the shard name suffix is used so that all partitions share the same base size
+		baseSize = getBaseShardSize(buildShardResource(nil, smParam, GetShardName(operatorIdentity, len(clusterConfigs)), operatorIdentity))
+		size     = baseSize
+	)
+	for _, clusterConfig := range clusterConfigs {
+		clusterIdentities := len(clusterConfig.IdentityConfig.AssetList)
+		clusterSize := getClusterShardSize(buildClusterShard(clusterConfig))
+		if exceedsShardLimits(1, clusterIdentities, baseSize+clusterSize, smParam) {
+			log.Warnf("cluster %s exceeds shard size limits on its own, it will be placed in a dedicated shard", clusterConfig.Name)
+		}
+		if len(partition) > 0 && exceedsShardLimits(len(partition)+1, identities+clusterIdentities, size+clusterSize, smParam) {
+			partitions = append(partitions, partition)
+			partition, identities, size = nil, 0, baseSize
+		}
+		partition = append(partition, clusterConfig)
+		identities += clusterIdentities
+		size += clusterSize
+	}
+	if len(partition) > 0 || len(partitions) == 0 {
+		partitions = append(partitions, partition)
+	}
+	return partitions
+}
+
+// merges parts of the same cluster assigned to an operator into a single cluster configuration holding the
+// identities of every part
+func mergeClusterConfigs(clusterConfigs []registry.ClusterConfig) []registry.ClusterConfig {
+	var (
+		merged []registry.ClusterConfig
+		index  = make(map[string]int)
+	)
+	for _, clusterConfig := range clusterConfigs {
+		clusterConfig.Partition = ""
+		i, ok := index[clusterConfig.Name]
+		if !ok {
+			index[clusterConfig.Name] = len(merged)
+			clusterConfig.IdentityConfig.AssetList = append([]registry.AssetList{}, clusterConfig.IdentityConfig.AssetList...)
+			merged = append(merged, clusterConfig)
+			continue
+		}
+		merged[i].IdentityConfig.AssetList = append(merged[i].IdentityConfig.AssetList, clusterConfig.IdentityConfig.AssetList...)
+	}
+	return merged
+}
+
+// returns a copy of cluster configuration sorted by cluster name, so that shards are built deterministically
+func sortClusterConfigs(clusterConfigs []registry.ClusterConfig) []registry.ClusterConfig {
+	sorted := make([]registry.ClusterConfig, len(clusterConfigs))
+	copy(sorted, clusterConfigs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func exceedsShardLimits(clusters int, identities int, size int, smParam *model.ShardingManagerParams) bool {
+	return (smParam.MaxClustersPerShard > 0 && clusters > smParam.MaxClustersPerShard) ||
+		(smParam.MaxIdentitiesPerShard > 0 && identities > smParam.MaxIdentitiesPerShard) ||
+		(smParam.MaxShardSizeBytes > 0 && size > smParam.MaxShardSizeBytes)
+}
+
+// serialized size of the shard resource
+func getShardSize(shard *typeV1.Shard) int {
+	data, err := json.Marshal(shard)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// serialized size of the shard resource without clusters, including the enclosing cluster list
+func getBaseShardSize(shard *typeV1.Shard) int {
+	shard.Spec.Clusters = []typeV1.ClusterShards{{}}
+	return getShardSize(shard) - getClusterShardSize(typeV1.ClusterShards{})
+}
+
+// serialized size added to a shard by a cluster, including the separator between list entries
+func getClusterShardSize(cluster typeV1.ClusterShards) int {
+	data, err := json.Marshal(cluster)
+	if err != nil {
+		return 0
+	}
+	return len(data) + 1
+}
+
+func buildClusterShard(clusterConfig registry.ClusterConfig) typeV1.ClusterShards {
+	cluster := typeV1.ClusterShards{
+		Name:     clusterConfig.Name,
+		Locality: clusterConfig.Locality,
+	}
+	var identities []typeV1.IdentityItem
+	for _, identityConfig := range clusterConfig.IdentityConfig.AssetList {
+		identity := typeV1.IdentityItem{
+			Name:        identityConfig.Name,
+			Environment: identityConfig.Environment,
+		}
+		identities = append(identities, identity)
+	}
+	sort.SliceStable(identities, func(i, j int) bool {
+		return identities[i].Name < identities[j].Name
+	})
+	cluster.Identities = identities
+	return cluster
+}
+
 func buildShardResource(clusterConfigs []registry.ClusterConfig, smParam *model.ShardingManagerParams, shardName string, operatorIdentity string) *typeV1.Shard {
 	var (
 		clusters []typeV1.ClusterShards
@@ -86,24 +246,15 @@ func buildShardResource(clusterConfigs []registry.ClusterConfig, smParam *model.
 	labels[ShardIdentity] = smParam.ShardingManagerIdentity
 	labels[smParam.OperatorIdentityLabel] = operatorIdentity
 
-	for _, clusterConfig := range clusterConfigs {
-		cluster := typeV1.ClusterShards{
-			Name:     clusterConfig.Name,
-			Locality: clusterConfig.Locality,
-		}
-		var identities []typeV1.IdentityItem
-		for _, identityConfig := range clusterConfig.IdentityConfig.AssetList {
-			identity := typeV1.IdentityItem{
-				Name:        identityConfig.Name,
-				Environment: identityConfig.Environment,
-			}
-			identities = append(identities, identity)
-		}
-		cluster.Identities = identities
-		clusters = append(clusters, cluster)
+	for _, clusterConfig := range sortClusterConfigs(clusterConfigs) {
+		clusters = append(clusters, buildClusterShard(clusterConfig))
 	}
 
 	shard := &typeV1.Shard{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: typeV1.SchemeGroupVersion.String(),
+			Kind:       ShardKind,
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      shardName,
 			Namespace: smParam.ShardNamespace,
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+func getTestClusterConfig(name string, identityCount int) registry.ClusterConfig {
+	cluster := registry.ClusterConfig{
+		Name:     name,
+		Locality: "us-west-2",
+	}
+	for i := 0; i < identityCount; i++ {
+		cluster.IdentityConfig.AssetList = append(cluster.IdentityConfig.AssetList, registry.AssetList{
+			Name:        fmt.Sprintf("%s-identity%d", name, i),
+			Environment: "qal",
+		})
+	}
+	return cluster
+}
+
+func getPartitionNames(partitions [][]registry.ClusterConfig) [][]string {
+	var names [][]string
+	for _, partition := range partitions {
+		partitionNames := []string{}
+		for _, cluster := range partition {
+			partitionNames = append(partitionNames, cluster.Name)
+		}
+		names = append(names, partitionNames)
+	}
+	return names
+}
+
+func TestPartitionClusterConfigs(t *testing.T) {
+	clusters := []registry.ClusterConfig{
+		getTestClusterConfig("cluster1", 2),
+		getTestClusterConfig("cluster2", 1),
+		getTestClusterConfig("cluster3", 3),
+	}
+	// parts of a cluster whose identities are distributed on their own
+	var clusterParts []registry.ClusterConfig
+	for _, asset := range getTestClusterConfig("cluster1", 2).IdentityConfig.AssetList {
+		part := getTestClusterConfig("cluster1", 0)
+		part.Partition = asset.Name
+		part.IdentityConfig.AssetList = []registry.AssetList{asset}
+		clusterParts = append(clusterParts, part)
+	}
+	// size limit fitting the first two clusters in a shard but not the third one
+	sizeParams := &model.ShardingManagerParams{}
+	sizeParams.MaxShardSizeBytes = getBaseShardSize(buildShardResource(nil, sizeParams, GetShardName("operator1", len(clusters)), "operator1")) +
+		getClusterShardSize(buildClusterShard(clusters[0])) + getClusterShardSize(buildClusterShard(clusters[1]))
+	testCases := []struct {
+		name               string
+		clusters           []registry.ClusterConfig
+		params             *model.ShardingManagerParams
+		expectedPartitions [][]string
+	}{
+		{
+			name: "Given no shard size limits, " +
+				"When cluster configuration is partitioned, " +
+				"Then all clusters should be part of a single partition",
+			clusters:           clusters,
+			params:             &model.ShardingManagerParams{},
+			expectedPartitions: [][]string{{"cluster1", "cluster2", "cluster3"}},
+		},
+		{
+			name: "Given a limit on clusters per shard, " +
+				"When cluster configuration is partitioned, " +
+				"Then no partition should exceed the limit",
+			clusters:           clusters,
+			params:             &model.ShardingManagerParams{MaxClustersPerShard: 2},
+			expectedPartitions: [][]string{{"cluster1", "cluster2"}, {"cluster3"}},
+		},
+		{
+			name: "Given a limit on identities per shard, " +
+				"When cluster configuration is partitioned, " +
+				"Then no partition should exceed the limit unless a single cluster exceeds it",
+			clusters:           clusters,
+			params:             &model.ShardingManagerParams{MaxIdentitiesPerShard: 2},
+			expectedPartitions: [][]string{{"cluster1"}, {"cluster2"}, {"cluster3"}},
+		},
+		{
+			name: "Given a limit on serialized shard size, " +
+				"When cluster configuration is partitioned, " +
+				"Then every partition should fit within the limit",
+			clusters:           clusters,
+			params:             sizeParams,
+			expectedPartitions: [][]string{{"cluster1", "cluster2"}, {"cluster3"}},
+		},
+		{
+			name: "Given parts of the same cluster and a limit on clusters per shard, " +
+				"When cluster configuration is partitioned, " +
+				"Then parts should be merged into a single cluster",
+			clusters:           append(clusterParts, getTestClusterConfig("cluster2", 1)),
+			params:             &model.ShardingManagerParams{MaxClustersPerShard: 1},
+			expectedPartitions: [][]string{{"cluster1"}, {"cluster2"}},
+		},
+		{
+			name: "Given no clusters, " +
+				"When cluster configuration is partitioned, " +
+				"Then there should be a single empty partition",
+			params:             &model.ShardingManagerParams{MaxClustersPerShard: 2},
+			expectedPartitions: [][]string{{}},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			partitions := partitionClusterConfigs(c.clusters, c.params, "operator1")
+			actualPartitions := getPartitionNames(partitions)
+			if !cmp.Equal(actualPartitions, c.expectedPartitions) {
+				t.Errorf(cmp.Diff(actualPartitions, c.expectedPartitions))
+			}
+			if c.params.MaxShardSizeBytes == 0 {
+				return
+			}
+			for index, partition := range partitions {
+				shard := buildShardResource(partition, c.params, GetShardName("operator1", index), "operator1")
+				if getShardSize(shard) > c.params.MaxShardSizeBytes {
+					t.Errorf("shard %d of size %d exceeds limit of %d bytes", index, getShardSize(shard), c.params.MaxShardSizeBytes)
+				}
+			}
+		})
+	}
+}
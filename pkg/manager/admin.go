@@ -0,0 +1,33 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+)
+
+// Interface to inspect sharding manager state through the admin api
+type AdminInterface interface {
+	// placement overrides applied and rejected by the last sync
+	GetOverrideStatus() model.OverrideStatus
+	// last n assignment changes recorded in the audit log
+	GetAuditEntries(n int) []model.AuditEntry
+	// clusters currently handed off between operators
+	GetMigrations() []model.Migration
+	// registry configuration rejected for removing too many clusters
+	GetRegistryGuardStatus() model.RegistryGuardStatus
+	// accepts removals of the rejected registry configuration so that the next sync applies it
+	AcceptRegistryChange() (model.RegistryChange, error)
+	// progress of the rollout of cluster moves and moves queued for later waves or rebalance windows
+	GetRolloutStatus() model.RolloutStatus
+	// revisions of the assignment kept in the history and the revision rebalancing is frozen at
+	GetRevisions() model.RevisionHistory
+	// re-applies a previous revision of the assignment and freezes rebalancing until it is resumed
+	Rollback(ctx context.Context, revision int) (model.AssignmentRevision, error)
+	// resumes rebalancing frozen by a rollback
+	Resume(ctx context.Context) error
+	// shard changes the last sync would have made in dry run mode
+	GetDryRunStatus() model.DryRunStatus
+	// applies settings which can change without a restart
+	UpdateParams(params model.ShardingManagerParams) error
+}
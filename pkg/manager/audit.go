@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+)
+
+// number of audit entries kept in memory when no size is configured
+const defaultAuditLogSize = 1000
+
+// append-only log of assignment changes written as json lines, the last entries are kept in memory
+// so that they can be queried through the admin api
+type auditLog struct {
+	mutex   sync.Mutex
+	writer  io.Writer
+	entries []model.AuditEntry
+	size    int
+}
+
+// opens the audit log sink, entries are only kept in memory when no path is configured
+func newAuditLog(path string, size int) (*auditLog, error) {
+	var writer io.Writer
+	switch path {
+	case "":
+	case model.StdoutAuditLogPath:
+		writer = os.Stdout
+	default:
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %v", err)
+		}
+		writer = file
+	}
+	if size <= 0 {
+		size = defaultAuditLogSize
+	}
+	return &auditLog{writer: writer, size: size}, nil
+}
+
+func (al *auditLog) record(entries []model.AuditEntry) {
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	for _, entry := range entries {
+		if al.writer != nil {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				logrus.Errorf("failed to marshal audit entry: %v", err)
+			} else if _, err = al.writer.Write(append(data, '\n')); err != nil {
+				logrus.Errorf("failed to write audit entry: %v", err)
+			}
+		}
+		al.entries = append(al.entries, entry)
+	}
+	if len(al.entries) > al.size {
+		al.entries = append([]model.AuditEntry{}, al.entries[len(al.entries)-al.size:]...)
+	}
+}
+
+// last n entries, oldest first
+func (al *auditLog) last(n int) []model.AuditEntry {
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	if n <= 0 || n > len(al.entries) {
+		n = len(al.entries)
+	}
+	return append([]model.AuditEntry{}, al.entries[len(al.entries)-n:]...)
+}
+
+// one entry for every cluster whose operator changed, clusters which were not moved for a specific reason
+// were moved by the periodic bulk sync picking up a registry or operator change
+func getAuditEntries(
+	previous map[string]string,
+	current map[string]string,
+	triggers map[string]string,
+	clusters []registry.ClusterConfig,
+	resourceVersion string,
+	now time.Time) []model.AuditEntry {
+	identities := make(map[string][]string)
+	for _, cluster := range clusters {
+		names := []string{}
+		for _, asset := range cluster.IdentityConfig.AssetList {
+			names = append(names, asset.Name)
+		}
+		sort.Strings(names)
+		identities[getClusterKey(cluster)] = names
+	}
+	changed := make(map[string]bool)
+	for cluster, operator := range current {
+		if previous[cluster] != operator {
+			changed[cluster] = true
+		}
+	}
+	for cluster := range previous {
+		if _, ok := current[cluster]; !ok {
+			changed[cluster] = true
+		}
+	}
+	var names []string
+	for cluster := range changed {
+		names = append(names, cluster)
+	}
+	sort.Strings(names)
+
+	var entries []model.AuditEntry
+	for _, cluster := range names {
+		trigger, ok := triggers[cluster]
+		if !ok {
+			trigger = model.BulkSyncAuditTrigger
+		}
+		entries = append(entries, model.AuditEntry{
+			Time:                    now,
+			Cluster:                 cluster,
+			Identities:              identities[cluster],
+			PreviousOperator:        previous[cluster],
+			Operator:                current[cluster],
+			Trigger:                 trigger,
+			RegistryResourceVersion: resourceVersion,
+		})
+	}
+	return entries
+}
+
+// records assignment changes of the last sync in the audit log
+func (sm *shardingManager) recordAudit(entries []model.AuditEntry) {
+	if sm.auditLog == nil {
+		return
+	}
+	sm.auditLog.record(entries)
+}
+
+// last n assignment changes, every kept change is returned when n is not positive
+func (sm *shardingManager) GetAuditEntries(n int) []model.AuditEntry {
+	if sm.auditLog == nil {
+		return []model.AuditEntry{}
+	}
+	return sm.auditLog.last(n)
+}
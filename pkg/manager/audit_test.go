@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+func TestGetAuditEntries(t *testing.T) {
+	clusters := []registry.ClusterConfig{
+		getTestCluster("cluster1", "identity2", "identity1"),
+		getTestCluster("cluster2"),
+		getTestCluster("cluster3", "identity3"),
+		getTestCluster("cluster4"),
+	}
+	testCases := []struct {
+		name            string
+		previous        map[string]string
+		current         map[string]string
+		triggers        map[string]string
+		expectedEntries []model.AuditEntry
+	}{
+		{
+			name: "Given an unchanged assignment, " +
+				"When audit entries are computed, " +
+				"Then there should be no entry",
+			previous: map[string]string{"cluster1": "operator1"},
+			current:  map[string]string{"cluster1": "operator1"},
+		},
+		{
+			name: "Given assigned, moved and removed clusters, " +
+				"When audit entries are computed, " +
+				"Then every change should be recorded with its trigger",
+			previous: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster4": "operator2"},
+			current:  map[string]string{"cluster1": "operator2", "cluster2": "operator1", "cluster3": "operator1"},
+			triggers: map[string]string{"cluster1": model.FailoverAuditTrigger},
+			expectedEntries: []model.AuditEntry{
+				{
+					Time:                    testNow,
+					Cluster:                 "cluster1",
+					Identities:              []string{"identity1", "identity2"},
+					PreviousOperator:        "operator1",
+					Operator:                "operator2",
+					Trigger:                 model.FailoverAuditTrigger,
+					RegistryResourceVersion: "42",
+				},
+				{
+					Time:                    testNow,
+					Cluster:                 "cluster3",
+					Identities:              []string{"identity3"},
+					Operator:                "operator1",
+					Trigger:                 model.BulkSyncAuditTrigger,
+					RegistryResourceVersion: "42",
+				},
+				{
+					Time:                    testNow,
+					Cluster:                 "cluster4",
+					Identities:              []string{},
+					PreviousOperator:        "operator2",
+					Trigger:                 model.BulkSyncAuditTrigger,
+					RegistryResourceVersion: "42",
+				},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actualEntries := getAuditEntries(c.previous, c.current, c.triggers, clusters, "42", testNow)
+			if !cmp.Equal(actualEntries, c.expectedEntries) {
+				t.Errorf(cmp.Diff(actualEntries, c.expectedEntries))
+			}
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := newAuditLog(path, 2)
+	if err != nil {
+		t.Fatalf("unexpected error while opening audit log: %v", err)
+	}
+	auditLog.record([]model.AuditEntry{{Cluster: "cluster1"}, {Cluster: "cluster2"}})
+	auditLog.record([]model.AuditEntry{{Cluster: "cluster3"}})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	var writtenClusters []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry model.AuditEntry
+		err = json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			t.Fatalf("audit log line %q is not a json entry: %v", line, err)
+		}
+		writtenClusters = append(writtenClusters, entry.Cluster)
+	}
+	expectedWritten := []string{"cluster1", "cluster2", "cluster3"}
+	if !cmp.Equal(writtenClusters, expectedWritten) {
+		t.Errorf(cmp.Diff(writtenClusters, expectedWritten))
+	}
+
+	testCases := []struct {
+		name             string
+		n                int
+		expectedClusters []string
+	}{
+		{
+			name: "Given more entries than kept in memory, " +
+				"When every entry is requested, " +
+				"Then only the most recent entries should be returned",
+			expectedClusters: []string{"cluster2", "cluster3"},
+		},
+		{
+			name: "Given entries kept in memory, " +
+				"When the last entry is requested, " +
+				"Then only the most recent entry should be returned",
+			n:                1,
+			expectedClusters: []string{"cluster3"},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var actualClusters []string
+			for _, entry := range auditLog.last(c.n) {
+				actualClusters = append(actualClusters, entry.Cluster)
+			}
+			if !cmp.Equal(actualClusters, c.expectedClusters) {
+				t.Errorf(cmp.Diff(actualClusters, c.expectedClusters))
+			}
+		})
+	}
+}
@@ -1,12 +1,21 @@
 package manager
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	admiralv1 "github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/typed/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
 	"github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 )
 
 // Interface to load kubernetes clients
@@ -18,6 +27,18 @@ type LoadKubeClient interface {
 	//loads admiral api client using kubernetes config
 	//Admiral api client is used to manage admiral resource on specified kubernetes cluster
 	LoadAdmiralApiClientFromConfig(config *rest.Config) (admiralv1.AdmiralV1Interface, error)
+
+	//loads kubernetes client using kubeconfig path
+	//Kubernetes client is used to discover admiral operators and record events on specified kubernetes cluster
+	LoadKubernetesClientFromPath(path string) (kubernetes.Interface, error)
+
+	//loads kubernetes client using kubernetes config
+	//Kubernetes client is used to discover admiral operators and record events on specified kubernetes cluster
+	LoadKubernetesClientFromConfig(config *rest.Config) (kubernetes.Interface, error)
+
+	//loads kubernetes config of the cluster sharding manager runs against
+	//in-cluster config is used when no kubeconfig path is set and sharding manager runs in a pod
+	LoadConfig(params *model.ShardingManagerParams) (*rest.Config, error)
 }
 
 type KubeClient struct{}
@@ -35,8 +56,124 @@ func (loader *KubeClient) LoadAdmiralApiClientFromConfig(config *rest.Config) (a
 	return admiralv1.NewForConfig(config)
 }
 
+func (loader *KubeClient) LoadKubernetesClientFromPath(kubeConfigPath string) (kubernetes.Interface, error) {
+	config, err := getConfig(kubeConfigPath)
+	if err != nil || config == nil {
+		return nil, err
+	}
+
+	return loader.LoadKubernetesClientFromConfig(config)
+}
+
+func (loader *KubeClient) LoadKubernetesClientFromConfig(config *rest.Config) (kubernetes.Interface, error) {
+	return kubernetes.NewForConfig(config)
+}
+
+func (loader *KubeClient) LoadConfig(params *model.ShardingManagerParams) (*rest.Config, error) {
+	var (
+		config *rest.Config
+		err    error
+	)
+	if params.KubeconfigPath == "" && params.KubeconfigContext == "" && isInCluster() {
+		logrus.Infof("using in-cluster kubeconfig")
+		config, err = rest.InClusterConfig()
+	} else {
+		// an explicit kubeconfig path takes precedence over KUBECONFIG and the default kubeconfig location
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = params.KubeconfigPath
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: params.KubeconfigContext}
+		logrus.Infof("getting kubeconfig from: %#v, context: %#v", params.KubeconfigPath, params.KubeconfigContext)
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve kubeconfig: %v", err)
+	}
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: params.ImpersonateUser,
+		Groups:   params.ImpersonateGroups,
+	}
+	if params.KubeClientQPS > 0 {
+		config.QPS = params.KubeClientQPS
+	}
+	if params.KubeClientBurst > 0 {
+		config.Burst = params.KubeClientBurst
+	}
+	return config, nil
+}
+
+// loads admiral api client and kubernetes client sharing the same kubernetes config
+func LoadKubeClients(loader LoadKubeClient, params *model.ShardingManagerParams) (admiralv1.AdmiralV1Interface, kubernetes.Interface, error) {
+	config, err := loader.LoadConfig(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	admiralAPIClient, err := loader.LoadAdmiralApiClientFromConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize admiral api client: %v", err)
+	}
+	kubernetesClient, err := loader.LoadKubernetesClientFromConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize kubernetes client: %v", err)
+	}
+	return admiralAPIClient, kubernetesClient, nil
+}
+
+// loads admiral api clients of the additional clusters shards are published to, from kubeconfig paths and from
+// secrets in the shard namespace matching the shard target secret selector. Every key of a matching secret is
+// a target name holding the kubeconfig of that target, the same way admiral defines remote clusters
+func LoadShardTargets(ctx context.Context, loader LoadKubeClient, kubernetesClient kubernetes.Interface, params *model.ShardingManagerParams) (map[string]admiralv1.AdmiralV1Interface, error) {
+	targets := make(map[string]admiralv1.AdmiralV1Interface)
+	for name, path := range params.ShardTargets {
+		if name == model.LocalShardTarget {
+			return nil, fmt.Errorf("shard target name %s is reserved for the local cluster", name)
+		}
+		client, err := loader.LoadAdmiralApiClientFromPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client of shard target %s: %v", name, err)
+		}
+		targets[name] = client
+	}
+	if params.ShardTargetSecretSelector == "" {
+		return targets, nil
+	}
+	secrets, err := kubernetesClient.CoreV1().Secrets(params.ShardNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: params.ShardTargetSecretSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shard target secrets: %v", err)
+	}
+	for _, secret := range secrets.Items {
+		for name, kubeconfig := range secret.Data {
+			if _, ok := targets[name]; ok || name == model.LocalShardTarget {
+				return nil, fmt.Errorf("shard target %s of secret %s is already defined", name, secret.Name)
+			}
+			config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+			if err != nil {
+				return nil, fmt.Errorf("invalid kubeconfig of shard target %s in secret %s: %v", name, secret.Name, err)
+			}
+			client, err := loader.LoadAdmiralApiClientFromConfig(config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client of shard target %s: %v", name, err)
+			}
+			targets[name] = client
+		}
+	}
+	return targets, nil
+}
+
+// initializes event recorder which records kubernetes events on behalf of sharding manager
+func NewEventRecorder(client kubernetes.Interface, component string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartStructuredLogging(0)
+	broadcaster.StartRecordingToSink(&typedCoreV1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, coreV1.EventSource{Component: component})
+}
+
 func getConfig(kubeConfigPath string) (*rest.Config, error) {
 	logrus.Infof("getting kubeconfig from: %#v", kubeConfigPath)
+	if kubeConfigPath == "" {
+		return nil, fmt.Errorf("could not retrieve kubeconfig: kubeconfig path is empty")
+	}
 	// create the config from the path
 	config, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
 
@@ -45,3 +182,8 @@ func getConfig(kubeConfigPath string) (*rest.Config, error) {
 	}
 	return config, err
 }
+
+// sharding manager runs in a pod when the service account environment is set by kubelet
+func isInCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
+}
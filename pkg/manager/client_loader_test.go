@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"k8s.io/client-go/rest"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster1
+  cluster:
+    server: https://cluster1.example.com
+- name: cluster2
+  cluster:
+    server: https://cluster2.example.com
+users:
+- name: user
+  user:
+    token: token
+contexts:
+- name: context1
+  context:
+    cluster: cluster1
+    user: user
+- name: context2
+  context:
+    cluster: cluster2
+    user: user
+current-context: context1
+`
+
+func TestLoadConfig(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig")
+	err := os.WriteFile(kubeconfigPath, []byte(testKubeconfig), 0600)
+	if err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	testCases := []struct {
+		name                string
+		params              model.ShardingManagerParams
+		expectedHost        string
+		expectedImpersonate rest.ImpersonationConfig
+		expectedQPS         float32
+		expectedBurst       int
+		expectedError       bool
+	}{
+		{
+			name: "Given a kubeconfig path, " +
+				"When config is loaded, " +
+				"Then the current context should be used with client default rate limits",
+			params:       model.ShardingManagerParams{KubeconfigPath: kubeconfigPath},
+			expectedHost: "https://cluster1.example.com",
+		},
+		{
+			name: "Given a kubeconfig path with context, impersonation and rate limits, " +
+				"When config is loaded, " +
+				"Then every setting should be applied",
+			params: model.ShardingManagerParams{
+				KubeconfigPath:    kubeconfigPath,
+				KubeconfigContext: "context2",
+				ImpersonateUser:   "sharding-manager",
+				ImpersonateGroups: []string{"admiral"},
+				KubeClientQPS:     50,
+				KubeClientBurst:   100,
+			},
+			expectedHost:        "https://cluster2.example.com",
+			expectedImpersonate: rest.ImpersonationConfig{UserName: "sharding-manager", Groups: []string{"admiral"}},
+			expectedQPS:         50,
+			expectedBurst:       100,
+		},
+		{
+			name: "Given an unknown context, " +
+				"When config is loaded, " +
+				"Then there should be non nil error",
+			params:        model.ShardingManagerParams{KubeconfigPath: kubeconfigPath, KubeconfigContext: "unknown"},
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			loader := &KubeClient{}
+			config, err := loader.LoadConfig(&c.params)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error while loading config")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error while loading config: %v", err)
+			}
+			if config.Host != c.expectedHost {
+				t.Errorf("expected host %s, got %s", c.expectedHost, config.Host)
+			}
+			if !cmp.Equal(config.Impersonate, c.expectedImpersonate) {
+				t.Errorf(cmp.Diff(config.Impersonate, c.expectedImpersonate))
+			}
+			if config.QPS != c.expectedQPS || config.Burst != c.expectedBurst {
+				t.Errorf("expected qps %v and burst %d, got %v and %d", c.expectedQPS, c.expectedBurst, config.QPS, config.Burst)
+			}
+		})
+	}
+}
@@ -7,11 +7,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	period = 10 * time.Second
-)
-
 func (sm *shardingManager) startPeriodicBulkSyncer(ctx context.Context) {
+	period := sm.getSyncPeriod()
 	ticker := time.NewTicker(period)
 	for {
 		select {
@@ -21,6 +18,13 @@ func (sm *shardingManager) startPeriodicBulkSyncer(ctx context.Context) {
 			if err != nil {
 				logrus.Errorf("failed to bulk sync: %v", err)
 			}
+			// sync period may have been changed by a configuration reload
+			updated := sm.getSyncPeriod()
+			if updated != period {
+				logrus.Infof("bulk sync period changed from %v to %v", period, updated)
+				period = updated
+				ticker.Reset(period)
+			}
 		case <-ctx.Done():
 			logrus.Warnf("stopping periodic bulk syncer")
 			ticker.Stop()
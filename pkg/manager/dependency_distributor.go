@@ -0,0 +1,320 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+)
+
+// share by which an operator's weighted load may exceed the average weighted load before clusters are no
+// longer placed with the clusters they depend on
+const dependencyImbalanceTolerance = 0.25
+
+var crossOperatorDependencies = monitoring.NewGauge(
+	"cross_operator_dependencies",
+	"number of dependencies between clusters handled by different operators",
+	monitoring.WithMeter(shardingManagerMeter))
+
+// clusters which host the source and the destination of the same identity depend on each other, the weight
+// of a dependency is the number of such identities
+type dependencyGraph map[string]map[string]int
+
+func buildDependencyGraph(clusters []registry.ClusterConfig) dependencyGraph {
+	var (
+		graph        = make(dependencyGraph)
+		sources      = make(map[string][]string)
+		destinations = make(map[string][]string)
+	)
+	for _, cluster := range clusters {
+		for _, asset := range cluster.IdentityConfig.AssetList {
+			if asset.SourceAsset {
+				sources[asset.Name] = append(sources[asset.Name], getClusterKey(cluster))
+			}
+			if asset.DestinationAsset {
+				destinations[asset.Name] = append(destinations[asset.Name], getClusterKey(cluster))
+			}
+		}
+	}
+	for identity, sourceClusters := range sources {
+		for _, source := range sourceClusters {
+			for _, destination := range destinations[identity] {
+				if source == destination {
+					continue
+				}
+				graph.addDependency(source, destination)
+				graph.addDependency(destination, source)
+			}
+		}
+	}
+	return graph
+}
+
+func (g dependencyGraph) addDependency(from string, to string) {
+	if g[from] == nil {
+		g[from] = make(map[string]int)
+	}
+	g[from][to]++
+}
+
+// total weight of dependencies between clusters handled by different operators, unassigned clusters are ignored
+func (g dependencyGraph) getCutSize(owners map[string]string) int {
+	var cut int
+	for from, dependencies := range g {
+		for to, weight := range dependencies {
+			fromOwner, fromOk := owners[from]
+			toOwner, toOk := owners[to]
+			if from < to && fromOk && toOk && fromOwner != toOwner {
+				cut += weight
+			}
+		}
+	}
+	return cut
+}
+
+// cut size of the dependency graph of the clusters in the assignment
+func getCrossOperatorDependencies(assignment model.ShardAssignment) int {
+	var clusters []registry.ClusterConfig
+	for _, assigned := range assignment {
+		clusters = append(clusters, assigned...)
+	}
+	return buildDependencyGraph(clusters).getCutSize(getOwners(assignment))
+}
+
+// assigns every unowned cluster to the operator handling most of the clusters it depends on, as long as that
+// operator is not overloaded yet, and falls back to the least loaded operator otherwise
+type dependencyAwareDistributor struct{}
+
+func NewDependencyAwareDistributor() *dependencyAwareDistributor {
+	return &dependencyAwareDistributor{}
+}
+
+func (d *dependencyAwareDistributor) Distribute(clusters []registry.ClusterConfig, operators []model.Operator, current map[string]string) (model.ShardAssignment, error) {
+	var (
+		assignment    = make(model.ShardAssignment)
+		load          = make(map[string]int)
+		owners        = make(map[string]string)
+		unowned       []registry.ClusterConfig
+		totalLoad     int
+		totalCapacity float64
+	)
+	for _, operator := range operators {
+		assignment[operator.Identity] = []registry.ClusterConfig{}
+		totalCapacity += getOperatorCapacity(operator)
+	}
+	for _, cluster := range clusters {
+		totalLoad += getClusterLoad(cluster)
+		owner, ok := current[getClusterKey(cluster)]
+		if !ok {
+			unowned = append(unowned, cluster)
+			continue
+		}
+		assignment[owner] = append(assignment[owner], cluster)
+		load[owner] += getClusterLoad(cluster)
+		owners[getClusterKey(cluster)] = owner
+	}
+	if len(unowned) > 0 && len(operators) == 0 {
+		return nil, fmt.Errorf("no operators available to assign %d clusters", len(unowned))
+	}
+
+	graph := buildDependencyGraph(clusters)
+	maxWeightedLoad := float64(totalLoad) / totalCapacity * (1 + dependencyImbalanceTolerance)
+	for _, cluster := range getDependencyOrder(unowned, graph) {
+		key := getClusterKey(cluster)
+		clusterLoad := getClusterLoad(cluster)
+		affinity := make(map[string]int)
+		for dependency, weight := range graph[key] {
+			if owner, ok := owners[dependency]; ok {
+				affinity[owner] += weight
+			}
+		}
+		var (
+			candidates  []model.Operator
+			maxAffinity int
+		)
+		for _, operator := range operators {
+			if affinity[operator.Identity] == 0 ||
+				float64(load[operator.Identity])/getOperatorCapacity(operator) >= maxWeightedLoad {
+				continue
+			}
+			if affinity[operator.Identity] > maxAffinity {
+				candidates, maxAffinity = nil, affinity[operator.Identity]
+			}
+			if affinity[operator.Identity] == maxAffinity {
+				candidates = append(candidates, operator)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = operators
+		}
+		target := leastLoadedOperator(load, clusterLoad, candidates)
+		assignment[target] = append(assignment[target], cluster)
+		load[target] += clusterLoad
+		owners[key] = target
+	}
+	return assignment, nil
+}
+
+// moves clusters which already have an operator to the operator handling more of the clusters they depend on,
+// so that switching an existing assignment to the dependency aware strategy also reduces cross operator
+// dependencies. Moves are only made while rebalancing is enabled, picking the move which reduces the cut most
+// first, as long as the target stays within the dependency imbalance tolerance and the imbalance between
+// operators within the rebalance threshold. Pinned clusters are never moved and every cluster is moved at most
+// once per sync. Returns the refined assignment along with the moved clusters
+func refineDependencyCut(
+	assignment model.ShardAssignment,
+	operators []model.Operator,
+	pinned map[string]string,
+	params *model.ShardingManagerParams) (model.ShardAssignment, []string) {
+	if params.RebalanceThreshold <= 0 {
+		return assignment, nil
+	}
+	var (
+		refined  = make(model.ShardAssignment)
+		clusters []registry.ClusterConfig
+		load     = make(map[string]int)
+		groups   = make(map[string][]model.Operator)
+		groupOf  = make(map[string]string)
+		moved    []string
+		movedSet = make(map[string]bool)
+	)
+	for operatorIdentity, assigned := range assignment {
+		refined[operatorIdentity] = append([]registry.ClusterConfig{}, assigned...)
+		clusters = append(clusters, assigned...)
+		for _, cluster := range assigned {
+			load[operatorIdentity] += getClusterLoad(cluster)
+		}
+	}
+	for _, operator := range operators {
+		group := getRebalanceGroup(operator, params)
+		groups[group] = append(groups[group], operator)
+		groupOf[operator.Identity] = group
+	}
+	maxWeightedLoad := make(map[string]float64)
+	for group, members := range groups {
+		var (
+			totalLoad     int
+			totalCapacity float64
+		)
+		for _, operator := range members {
+			totalLoad += load[operator.Identity]
+			totalCapacity += getOperatorCapacity(operator)
+		}
+		maxWeightedLoad[group] = float64(totalLoad) / totalCapacity * (1 + dependencyImbalanceTolerance)
+	}
+	graph := buildDependencyGraph(clusters)
+	owners := getOwners(refined)
+	sort.Slice(clusters, func(i, j int) bool {
+		return getClusterKey(clusters[i]) < getClusterKey(clusters[j])
+	})
+
+	for {
+		var (
+			best     registry.ClusterConfig
+			source   string
+			target   string
+			bestGain int
+		)
+		for _, cluster := range clusters {
+			key := getClusterKey(cluster)
+			owner := owners[key]
+			group, available := groupOf[owner]
+			if _, ok := pinned[key]; ok || movedSet[key] || !available {
+				continue
+			}
+			affinity := make(map[string]int)
+			for dependency, weight := range graph[key] {
+				affinity[owners[dependency]] += weight
+			}
+			clusterLoad := getClusterLoad(cluster)
+			for _, operator := range groups[group] {
+				gain := affinity[operator.Identity] - affinity[owner]
+				if operator.Identity == owner || gain <= bestGain ||
+					float64(load[operator.Identity]+clusterLoad)/getOperatorCapacity(operator) > maxWeightedLoad[group] {
+					continue
+				}
+				load[owner] -= clusterLoad
+				load[operator.Identity] += clusterLoad
+				imbalance := getImbalance(load, groups[group])
+				load[owner] += clusterLoad
+				load[operator.Identity] -= clusterLoad
+				if imbalance > params.RebalanceThreshold {
+					continue
+				}
+				best, source, target, bestGain = cluster, owner, operator.Identity, gain
+			}
+		}
+		if bestGain == 0 {
+			break
+		}
+		key := getClusterKey(best)
+		for i, cluster := range refined[source] {
+			if getClusterKey(cluster) == key {
+				refined[source] = append(refined[source][:i], refined[source][i+1:]...)
+				break
+			}
+		}
+		refined[target] = append(refined[target], best)
+		load[source] -= getClusterLoad(best)
+		load[target] += getClusterLoad(best)
+		owners[key] = target
+		movedSet[key] = true
+		moved = append(moved, key)
+		logrus.Infof("moving cluster %s from operator %s to operator %s to reduce cross operator dependencies by %d", key, source, target, bestGain)
+	}
+	sort.Strings(moved)
+	return refined, moved
+}
+
+// orders clusters so that clusters which depend on each other are placed one after the other, starting with
+// the heaviest cluster of every group and following the strongest dependencies first
+func getDependencyOrder(clusters []registry.ClusterConfig, graph dependencyGraph) []registry.ClusterConfig {
+	byName := make(map[string]registry.ClusterConfig)
+	for _, cluster := range clusters {
+		byName[getClusterKey(cluster)] = cluster
+	}
+	seeds := append([]registry.ClusterConfig{}, clusters...)
+	sort.Slice(seeds, func(i, j int) bool {
+		if getClusterLoad(seeds[i]) != getClusterLoad(seeds[j]) {
+			return getClusterLoad(seeds[i]) > getClusterLoad(seeds[j])
+		}
+		return getClusterKey(seeds[i]) < getClusterKey(seeds[j])
+	})
+
+	var (
+		order   []registry.ClusterConfig
+		visited = make(map[string]bool)
+	)
+	for _, seed := range seeds {
+		if visited[getClusterKey(seed)] {
+			continue
+		}
+		visited[getClusterKey(seed)] = true
+		queue := []string{getClusterKey(seed)}
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			order = append(order, byName[name])
+			var dependencies []string
+			for dependency := range graph[name] {
+				if _, ok := byName[dependency]; ok && !visited[dependency] {
+					dependencies = append(dependencies, dependency)
+				}
+			}
+			sort.Slice(dependencies, func(i, j int) bool {
+				if graph[name][dependencies[i]] != graph[name][dependencies[j]] {
+					return graph[name][dependencies[i]] > graph[name][dependencies[j]]
+				}
+				return dependencies[i] < dependencies[j]
+			})
+			for _, dependency := range dependencies {
+				visited[dependency] = true
+				queue = append(queue, dependency)
+			}
+		}
+	}
+	return order
+}
@@ -0,0 +1,291 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+// cluster hosting the source of the provided sources and the destination of the provided destinations
+func getTestDependencyCluster(name string, sources []string, destinations []string) registry.ClusterConfig {
+	cluster := getTestCluster(name)
+	for _, source := range sources {
+		cluster.IdentityConfig.AssetList = append(cluster.IdentityConfig.AssetList, registry.AssetList{
+			Name:        source,
+			Environment: "qal",
+			SourceAsset: true,
+		})
+	}
+	for _, destination := range destinations {
+		cluster.IdentityConfig.AssetList = append(cluster.IdentityConfig.AssetList, registry.AssetList{
+			Name:             destination,
+			Environment:      "qal",
+			DestinationAsset: true,
+		})
+	}
+	return cluster
+}
+
+func TestBuildDependencyGraph(t *testing.T) {
+	clusters := []registry.ClusterConfig{
+		getTestDependencyCluster("cluster1", []string{"identity1", "identity2"}, nil),
+		getTestDependencyCluster("cluster2", nil, []string{"identity1", "identity2"}),
+		getTestDependencyCluster("cluster3", []string{"identity3"}, []string{"identity1"}),
+		getTestDependencyCluster("cluster4", []string{"identity4"}, nil),
+	}
+	expectedGraph := dependencyGraph{
+		"cluster1": {"cluster2": 2, "cluster3": 1},
+		"cluster2": {"cluster1": 2},
+		"cluster3": {"cluster1": 1},
+	}
+	actualGraph := buildDependencyGraph(clusters)
+	if !cmp.Equal(actualGraph, expectedGraph) {
+		t.Errorf(cmp.Diff(actualGraph, expectedGraph))
+	}
+
+	testCases := []struct {
+		name            string
+		owners          map[string]string
+		expectedCutSize int
+	}{
+		{
+			name: "Given dependent clusters handled by the same operator, " +
+				"When cut size is computed, " +
+				"Then it should be zero",
+			owners: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1", "cluster4": "operator2"},
+		},
+		{
+			name: "Given dependent clusters handled by different operators, " +
+				"When cut size is computed, " +
+				"Then it should be the weight of the dependencies between them",
+			owners:          map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator2"},
+			expectedCutSize: 3,
+		},
+		{
+			name: "Given an unassigned cluster, " +
+				"When cut size is computed, " +
+				"Then its dependencies should be ignored",
+			owners:          map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+			expectedCutSize: 2,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actualCutSize := actualGraph.getCutSize(c.owners)
+			if actualCutSize != c.expectedCutSize {
+				t.Errorf("expected cut size %d, got %d", c.expectedCutSize, actualCutSize)
+			}
+		})
+	}
+}
+
+func TestDependencyAwareDistributor(t *testing.T) {
+	testCases := []struct {
+		name           string
+		clusters       []registry.ClusterConfig
+		operators      []model.Operator
+		current        map[string]string
+		expectedOwners map[string]string
+		expectedError  bool
+	}{
+		{
+			name: "Given two groups of dependent clusters, " +
+				"When clusters are distributed, " +
+				"Then every group should be kept on one operator",
+			clusters: []registry.ClusterConfig{
+				getTestDependencyCluster("cluster1", []string{"identity1"}, nil),
+				getTestDependencyCluster("cluster2", []string{"identity2"}, nil),
+				getTestDependencyCluster("cluster3", nil, []string{"identity1"}),
+				getTestDependencyCluster("cluster4", nil, []string{"identity2"}),
+			},
+			operators:      []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}},
+			current:        map[string]string{},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster3": "operator1", "cluster2": "operator2", "cluster4": "operator2"},
+		},
+		{
+			name: "Given a cluster depending on an already assigned cluster, " +
+				"When clusters are distributed, " +
+				"Then it should be placed with the cluster it depends on",
+			clusters: []registry.ClusterConfig{
+				getTestDependencyCluster("cluster1", []string{"identity1"}, nil),
+				getTestDependencyCluster("cluster2", []string{"identity2"}, nil),
+				getTestDependencyCluster("cluster3", nil, []string{"identity2"}),
+			},
+			operators:      []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}},
+			current:        map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator2"},
+		},
+		{
+			name: "Given a group of dependent clusters too large for one operator, " +
+				"When clusters are distributed, " +
+				"Then clusters should be spread once the operator is overloaded",
+			clusters: []registry.ClusterConfig{
+				getTestDependencyCluster("cluster1", []string{"identity1"}, nil),
+				getTestDependencyCluster("cluster2", nil, []string{"identity1"}),
+				getTestDependencyCluster("cluster3", nil, []string{"identity1"}),
+				getTestDependencyCluster("cluster4", nil, []string{"identity1"}),
+			},
+			operators:      []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}},
+			current:        map[string]string{},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1", "cluster4": "operator2"},
+		},
+		{
+			name: "Given unassigned clusters and no operators, " +
+				"When clusters are distributed, " +
+				"Then there should be non nil error",
+			clusters:      []registry.ClusterConfig{getTestCluster("cluster1", "identity1")},
+			current:       map[string]string{},
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, err := NewDependencyAwareDistributor().Distribute(c.clusters, c.operators, c.current)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error while distributing clusters")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error while distributing clusters: %v", err)
+			}
+			actualOwners := getOwners(assignment)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+		})
+	}
+}
+
+// live assignment placing both groups of dependent clusters across operators
+func getTestPoorCutAssignment() model.ShardAssignment {
+	return model.ShardAssignment{
+		"operator1": {
+			getTestDependencyCluster("cluster1", []string{"identity1"}, nil),
+			getTestDependencyCluster("cluster3", []string{"identity2"}, nil),
+			getTestDependencyCluster("cluster5", []string{"identity5", "identity6"}, nil),
+		},
+		"operator2": {
+			getTestDependencyCluster("cluster2", nil, []string{"identity1"}),
+			getTestDependencyCluster("cluster4", nil, []string{"identity2"}),
+			getTestDependencyCluster("cluster6", []string{"identity7", "identity8"}, nil),
+		},
+	}
+}
+
+func TestRefineDependencyCut(t *testing.T) {
+	operators := []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}}
+	testCases := []struct {
+		name           string
+		threshold      float64
+		pinned         map[string]string
+		expectedOwners map[string]string
+		expectedMoved  []string
+	}{
+		{
+			name: "Given no rebalance threshold, " +
+				"When the cut of a live assignment is refined, " +
+				"Then the assignment should be kept",
+			expectedOwners: getOwners(getTestPoorCutAssignment()),
+		},
+		{
+			name: "Given a live assignment with a poor cut, " +
+				"When the cut is refined, " +
+				"Then clusters should be moved to the operator of the clusters they depend on within the load tolerance",
+			threshold: 0.6,
+			expectedOwners: map[string]string{
+				"cluster1": "operator2", "cluster2": "operator2", "cluster6": "operator2",
+				"cluster3": "operator1", "cluster4": "operator1", "cluster5": "operator1",
+			},
+			expectedMoved: []string{"cluster1", "cluster4"},
+		},
+		{
+			name: "Given pinned clusters, " +
+				"When the cut is refined, " +
+				"Then pinned clusters should not be moved",
+			threshold: 0.6,
+			pinned:    map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+			expectedOwners: map[string]string{
+				"cluster1": "operator1", "cluster5": "operator1",
+				"cluster2": "operator2", "cluster3": "operator2", "cluster4": "operator2", "cluster6": "operator2",
+			},
+			expectedMoved: []string{"cluster3"},
+		},
+		{
+			name: "Given a rebalance threshold every move would exceed, " +
+				"When the cut is refined, " +
+				"Then the assignment should be kept",
+			threshold:      0.4,
+			expectedOwners: getOwners(getTestPoorCutAssignment()),
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			assignment := getTestPoorCutAssignment()
+			refined, moved := refineDependencyCut(assignment, operators, c.pinned, &model.ShardingManagerParams{RebalanceThreshold: c.threshold})
+			actualOwners := getOwners(refined)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+			if !cmp.Equal(moved, c.expectedMoved) {
+				t.Errorf(cmp.Diff(moved, c.expectedMoved))
+			}
+			if !cmp.Equal(getOwners(assignment), getOwners(getTestPoorCutAssignment())) {
+				t.Errorf("expected the provided assignment not to be modified")
+			}
+		})
+	}
+}
+
+func TestDeriveShardConfigurationRefinesDependencyCut(t *testing.T) {
+	live := getTestPoorCutAssignment()
+	var clusters []registry.ClusterConfig
+	for _, assigned := range live {
+		clusters = append(clusters, assigned...)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, getOwners(live), map[string]string{})
+	sm.cache.ClusterCache = clusters
+	sm.loadDistributor = NewDependencyAwareDistributor()
+	sm.params.DistributionStrategy = model.DependencyAwareStrategy
+	sm.params.RebalanceThreshold = 0.6
+	sm.params.RolloutMaxMoves = 1
+	sm.params.RolloutInterval = time.Minute
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	operators := []model.Operator{getTestOperator("operator1", testNow), getTestOperator("operator2", testNow)}
+
+	assignment, reconciliation, err := sm.deriveShardConfiguration(operators, testNow)
+	if err != nil {
+		t.Fatalf("unexpected error while deriving shard configuration: %v", err)
+	}
+	expectedTriggers := map[string]string{"cluster1": model.RebalanceAuditTrigger, "cluster4": model.RebalanceAuditTrigger}
+	if !cmp.Equal(reconciliation.triggers, expectedTriggers) {
+		t.Errorf(cmp.Diff(reconciliation.triggers, expectedTriggers))
+	}
+	if cut := getCrossOperatorDependencies(assignment); cut != 0 {
+		t.Errorf("expected no cross operator dependencies, got %d", cut)
+	}
+
+	// moves reducing the cut are rolled out like any other rebalance move
+	_, status := sm.limitRollout(context.Background(), assignment, reconciliation.triggers, operators, reconciliation.available, testNow)
+	if status.Moved != 1 {
+		t.Errorf("expected 1 moved cluster, got %d", status.Moved)
+	}
+	expectedPending := []model.RolloutMove{{Cluster: "cluster4", From: "operator2", To: "operator1"}}
+	if !cmp.Equal(status.Pending, expectedPending) {
+		t.Errorf(cmp.Diff(status.Pending, expectedPending))
+	}
+}
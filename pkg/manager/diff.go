@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+)
+
+// compares desired shards with live shards and returns the differences of every shard which drifted
+// shards which only exist on one side are reported with all their clusters added or removed
+func DiffShards(desired []*typeV1.Shard, live []typeV1.Shard, operatorIdentityLabel string) []model.ShardDiff {
+	var (
+		diffs         []model.ShardDiff
+		desiredByName = make(map[string]*typeV1.Shard)
+		liveByName    = make(map[string]*typeV1.Shard)
+		names         []string
+	)
+	for _, shard := range desired {
+		desiredByName[shard.Name] = shard
+		names = append(names, shard.Name)
+	}
+	for i := range live {
+		liveByName[live[i].Name] = &live[i]
+		if _, ok := desiredByName[live[i].Name]; !ok {
+			names = append(names, live[i].Name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		desiredClusters, desiredIdentities := getShardContents(desiredByName[name])
+		liveClusters, liveIdentities := getShardContents(liveByName[name])
+		diff := model.ShardDiff{
+			Shard:             name,
+			AddedClusters:     difference(desiredClusters, liveClusters),
+			RemovedClusters:   difference(liveClusters, desiredClusters),
+			AddedIdentities:   difference(desiredIdentities, liveIdentities),
+			RemovedIdentities: difference(liveIdentities, desiredIdentities),
+		}
+		_, inDesired := desiredByName[name]
+		_, inLive := liveByName[name]
+		if inDesired {
+			diff.Operator = desiredByName[name].Labels[operatorIdentityLabel]
+		} else {
+			diff.Operator = liveByName[name].Labels[operatorIdentityLabel]
+		}
+		if inDesired == inLive && len(diff.AddedClusters)+len(diff.RemovedClusters)+len(diff.AddedIdentities)+len(diff.RemovedIdentities) == 0 {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// returns cluster names and <cluster>/<identity> entries of a shard
+func getShardContents(shard *typeV1.Shard) (map[string]bool, map[string]bool) {
+	clusters := make(map[string]bool)
+	identities := make(map[string]bool)
+	if shard == nil {
+		return clusters, identities
+	}
+	for _, cluster := range shard.Spec.Clusters {
+		clusters[cluster.Name] = true
+		for _, identity := range cluster.Identities {
+			identities[fmt.Sprintf("%s/%s", cluster.Name, identity.Name)] = true
+		}
+	}
+	return clusters, identities
+}
+
+// sorted entries of a which are not in b
+func difference(a map[string]bool, b map[string]bool) []string {
+	var entries []string
+	for entry := range a {
+		if !b[entry] {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
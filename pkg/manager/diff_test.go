@@ -0,0 +1,156 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testOperatorIdentityLabel = "admiral.io/operatorIdentity"
+
+func getTestShard(name string, operatorIdentity string, clusters map[string][]string) typeV1.Shard {
+	shard := typeV1.Shard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{testOperatorIdentityLabel: operatorIdentity},
+		},
+	}
+	for cluster, identities := range clusters {
+		clusterShard := typeV1.ClusterShards{Name: cluster}
+		for _, identity := range identities {
+			clusterShard.Identities = append(clusterShard.Identities, typeV1.IdentityItem{Name: identity})
+		}
+		shard.Spec.Clusters = append(shard.Spec.Clusters, clusterShard)
+	}
+	return shard
+}
+
+func TestDiffShards(t *testing.T) {
+	unchanged := getTestShard("shard-operator1-0", "operator1", map[string][]string{"cluster1": {"identity1"}})
+	desiredChanged := getTestShard("shard-operator2-0", "operator2", map[string][]string{"cluster2": {"identity2", "identity3"}, "cluster3": nil})
+	liveChanged := getTestShard("shard-operator2-0", "operator2", map[string][]string{"cluster2": {"identity2"}, "cluster4": {"identity4"}})
+	added := getTestShard("shard-operator3-0", "operator3", map[string][]string{})
+	removed := getTestShard("shard-operator4-0", "operator4", map[string][]string{"cluster5": nil})
+
+	testCases := []struct {
+		name          string
+		desired       []*typeV1.Shard
+		live          []typeV1.Shard
+		expectedDiffs []model.ShardDiff
+	}{
+		{
+			name: "Given desired shards matching live shards, " +
+				"When shards are compared, " +
+				"Then there should be no difference",
+			desired: []*typeV1.Shard{&unchanged},
+			live:    []typeV1.Shard{unchanged},
+		},
+		{
+			name: "Given desired shards which drifted from live shards, " +
+				"When shards are compared, " +
+				"Then added and removed clusters and identities should be reported per shard",
+			desired: []*typeV1.Shard{&unchanged, &desiredChanged, &added},
+			live:    []typeV1.Shard{unchanged, liveChanged, removed},
+			expectedDiffs: []model.ShardDiff{
+				{
+					Shard:             "shard-operator2-0",
+					Operator:          "operator2",
+					AddedClusters:     []string{"cluster3"},
+					RemovedClusters:   []string{"cluster4"},
+					AddedIdentities:   []string{"cluster2/identity3"},
+					RemovedIdentities: []string{"cluster4/identity4"},
+				},
+				{
+					Shard:    "shard-operator3-0",
+					Operator: "operator3",
+				},
+				{
+					Shard:           "shard-operator4-0",
+					Operator:        "operator4",
+					RemovedClusters: []string{"cluster5"},
+				},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actualDiffs := DiffShards(c.desired, c.live, testOperatorIdentityLabel)
+			if !cmp.Equal(actualDiffs, c.expectedDiffs) {
+				t.Errorf(cmp.Diff(actualDiffs, c.expectedDiffs))
+			}
+		})
+	}
+}
+
+func TestDesiredShards(t *testing.T) {
+	testCases := []struct {
+		name           string
+		handoffTimeout time.Duration
+		expectedDiffs  []model.ShardDiff
+	}{
+		{
+			name: "Given a draining operator, " +
+				"When desired shards are computed, " +
+				"Then its cluster should be moved to the schedulable operator",
+			expectedDiffs: []model.ShardDiff{
+				{Shard: controller.GetShardName("operator1", 0), Operator: "operator1",
+					RemovedClusters: []string{"cluster1"}, RemovedIdentities: []string{"cluster1/identity1"}},
+				{Shard: controller.GetShardName("operator2", 0), Operator: "operator2",
+					AddedClusters: []string{"cluster1"}, AddedIdentities: []string{"cluster1/identity1"}},
+			},
+		},
+		{
+			name: "Given a draining operator and a handoff timeout, " +
+				"When desired shards are computed, " +
+				"Then its cluster should be kept by the draining operator until it is handed off",
+			handoffTimeout: time.Minute,
+			expectedDiffs: []model.ShardDiff{
+				{Shard: controller.GetShardName("operator2", 0), Operator: "operator2",
+					AddedClusters: []string{"cluster1"}, AddedIdentities: []string{"cluster1/identity1"}},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+			sm.params.ShardNamespace = "shard-namespace"
+			sm.params.ShardingManagerIdentity = "dev"
+			sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+			sm.params.HandoffTimeout = c.handoffTimeout
+			sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+			sm.registryClient = &testRegistryClient{
+				clusters:        []registry.ClusterConfig{getTestCluster("cluster1", "identity1"), getTestCluster("cluster2", "identity2")},
+				resourceVersion: "1",
+			}
+			draining := getTestOperator("operator1", time.Now())
+			draining.SchedulingState = model.DrainingSchedulingState
+			sm.operatorHandler = &testOperatorHandler{operators: []model.Operator{draining, getTestOperator("operator2", time.Now())}}
+			sm.migrations = make(map[string]model.Migration)
+			err := sm.pushShardConfiguration(ctx, model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1")},
+				"operator2": {getTestCluster("cluster2", "identity2")},
+			})
+			if err != nil {
+				t.Fatalf("failed to push live shards: %v", err)
+			}
+			sm.params.DryRun = true
+			desired, live, err := sm.DesiredShards(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error while computing desired shards: %v", err)
+			}
+			diffs := DiffShards(desired, live, testOperatorIdentityLabel)
+			if !cmp.Equal(diffs, c.expectedDiffs) {
+				t.Errorf(cmp.Diff(diffs, c.expectedDiffs))
+			}
+		})
+	}
+}
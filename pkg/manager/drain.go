@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"sort"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+// operators which are cordoned or draining do not receive new clusters
+func isSchedulable(operator model.Operator) bool {
+	return operator.SchedulingState != model.CordonedSchedulingState &&
+		operator.SchedulingState != model.DrainingSchedulingState
+}
+
+// releases up to the drain batch size of clusters from every draining operator so that they are
+// redistributed amongst schedulable operators, pinned clusters are never released
+// returns the released clusters
+func drainOperators(current map[string]string, pinned map[string]string, operators []model.Operator, batchSize int) []string {
+	draining := make(map[string][]string)
+	for _, operator := range operators {
+		if operator.SchedulingState == model.DrainingSchedulingState {
+			draining[operator.Identity] = []string{}
+		}
+	}
+	if len(draining) == 0 {
+		return nil
+	}
+	for cluster, owner := range current {
+		if _, ok := draining[owner]; !ok {
+			continue
+		}
+		if _, ok := pinned[cluster]; ok {
+			logrus.Warnf("cluster %s is pinned to draining operator %s and will not be moved", cluster, owner)
+			continue
+		}
+		draining[owner] = append(draining[owner], cluster)
+	}
+	var released []string
+	for operator, clusters := range draining {
+		sort.Strings(clusters)
+		if batchSize > 0 && len(clusters) > batchSize {
+			clusters = clusters[:batchSize]
+		}
+		for _, cluster := range clusters {
+			delete(current, cluster)
+		}
+		released = append(released, clusters...)
+		if len(clusters) > 0 {
+			logrus.Infof("moving %d clusters away from draining operator %s: %v", len(clusters), operator, clusters)
+		}
+	}
+	sort.Strings(released)
+	return released
+}
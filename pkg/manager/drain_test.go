@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+)
+
+func TestDrainOperators(t *testing.T) {
+	testCases := []struct {
+		name            string
+		current         map[string]string
+		pinned          map[string]string
+		operators       []model.Operator
+		batchSize       int
+		expectedCurrent map[string]string
+	}{
+		{
+			name: "Given a draining operator, " +
+				"When operators are drained, " +
+				"Then at most batch size clusters should be released from it",
+			current: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1", "cluster4": "operator2"},
+			pinned:  map[string]string{},
+			operators: []model.Operator{
+				{Identity: "operator1", SchedulingState: model.DrainingSchedulingState},
+				{Identity: "operator2"},
+			},
+			batchSize:       2,
+			expectedCurrent: map[string]string{"cluster3": "operator1", "cluster4": "operator2"},
+		},
+		{
+			name: "Given a cordoned operator, " +
+				"When operators are drained, " +
+				"Then it should keep its clusters",
+			current: map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+			pinned:  map[string]string{},
+			operators: []model.Operator{
+				{Identity: "operator1", SchedulingState: model.CordonedSchedulingState},
+				{Identity: "operator2"},
+			},
+			batchSize:       2,
+			expectedCurrent: map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+		},
+		{
+			name: "Given a draining operator with a pinned cluster and no batch size, " +
+				"When operators are drained, " +
+				"Then every cluster except the pinned one should be released",
+			current: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			pinned:  map[string]string{"cluster2": "operator1"},
+			operators: []model.Operator{
+				{Identity: "operator1", SchedulingState: model.DrainingSchedulingState},
+			},
+			expectedCurrent: map[string]string{"cluster2": "operator1"},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			drainOperators(c.current, c.pinned, c.operators, c.batchSize)
+			if !cmp.Equal(c.current, c.expectedCurrent) {
+				t.Errorf(cmp.Diff(c.current, c.expectedCurrent))
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"time"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+var (
+	dryRunShardChangesTotal = monitoring.NewCounter(
+		"dry_run_shard_changes_total",
+		"total number of shard creations, updates and deletions skipped in dry run mode",
+		monitoring.WithMeter(shardingManagerMeter))
+	dryRunPendingShardChanges = monitoring.NewGauge(
+		"dry_run_pending_shard_changes",
+		"number of shard changes the last sync would have made in dry run mode",
+		monitoring.WithMeter(shardingManagerMeter))
+)
+
+// reports the shard changes which the assignment would make to the live shards instead of making them
+func (sm *shardingManager) reportDryRun(assignment model.ShardAssignment, live []typeV1.Shard, now time.Time) {
+	changes := getDryRunChanges(controller.BuildShardResources(assignment, sm.params), live, sm.params.OperatorIdentityLabel)
+	for _, change := range changes {
+		logrus.Infof("dry run: would %s shard %s of operator %s, clusters added %v removed %v, identities added %v removed %v",
+			change.Operation, change.Shard, change.Operator, change.AddedClusters, change.RemovedClusters,
+			change.AddedIdentities, change.RemovedIdentities)
+		dryRunShardChangesTotal.Increment(api.WithAttributes(attribute.Key("operation").String(change.Operation)))
+	}
+	dryRunPendingShardChanges.Set(int64(len(changes)))
+	sm.dryRun = model.DryRunStatus{
+		Enabled:                 true,
+		Time:                    now,
+		RegistryResourceVersion: sm.cache.ResourceVersion,
+		Changes:                 changes,
+	}
+}
+
+// shards which only exist in the desired state would be created and shards which only exist in the live state
+// would be deleted, other drifted shards would be updated
+func getDryRunChanges(desired []*typeV1.Shard, live []typeV1.Shard, operatorIdentityLabel string) []model.DryRunChange {
+	var (
+		desiredNames = make(map[string]bool)
+		liveNames    = make(map[string]bool)
+		changes      = []model.DryRunChange{}
+	)
+	for _, shard := range desired {
+		desiredNames[shard.Name] = true
+	}
+	for _, shard := range live {
+		liveNames[shard.Name] = true
+	}
+	for _, diff := range DiffShards(desired, live, operatorIdentityLabel) {
+		operation := model.UpdateShardOperation
+		switch {
+		case !liveNames[diff.Shard]:
+			operation = model.CreateShardOperation
+		case !desiredNames[diff.Shard]:
+			operation = model.DeleteShardOperation
+		}
+		changes = append(changes, model.DryRunChange{Operation: operation, ShardDiff: diff})
+	}
+	return changes
+}
+
+// shard changes the last sync would have made in dry run mode
+func (sm *shardingManager) GetDryRunStatus() model.DryRunStatus {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	status := sm.dryRun
+	status.Changes = append([]model.DryRunChange{}, sm.dryRun.Changes...)
+	return status
+}
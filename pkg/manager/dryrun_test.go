@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestPreviewShardConfigurationDryRun(t *testing.T) {
+	live := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster1", "identity1")},
+		"operator2": {getTestCluster("cluster2", "identity2")},
+	}
+	testCases := []struct {
+		name            string
+		assignment      model.ShardAssignment
+		expectedChanges []model.DryRunChange
+	}{
+		{
+			name: "Given an assignment matching the live shards, " +
+				"When shard configuration is previewed in dry run mode, " +
+				"Then no change should be reported",
+			assignment:      live,
+			expectedChanges: []model.DryRunChange{},
+		},
+		{
+			name: "Given an assignment which changes, adds and removes shards, " +
+				"When shard configuration is previewed in dry run mode, " +
+				"Then every skipped change should be reported",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1", "identity3")},
+				"operator3": {getTestCluster("cluster2", "identity2")},
+			},
+			expectedChanges: []model.DryRunChange{
+				{Operation: model.UpdateShardOperation, ShardDiff: model.ShardDiff{
+					Shard:           controller.GetShardName("operator1", 0),
+					Operator:        "operator1",
+					AddedIdentities: []string{"cluster1/identity3"},
+				}},
+				{Operation: model.DeleteShardOperation, ShardDiff: model.ShardDiff{
+					Shard:             controller.GetShardName("operator2", 0),
+					Operator:          "operator2",
+					RemovedClusters:   []string{"cluster2"},
+					RemovedIdentities: []string{"cluster2/identity2"},
+				}},
+				{Operation: model.CreateShardOperation, ShardDiff: model.ShardDiff{
+					Shard:           controller.GetShardName("operator3", 0),
+					Operator:        "operator3",
+					AddedClusters:   []string{"cluster2"},
+					AddedIdentities: []string{"cluster2/identity2"},
+				}},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+			sm.params.ShardNamespace = "shard-namespace"
+			sm.params.ShardingManagerIdentity = "dev"
+			sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+			sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+			err := sm.pushShardConfiguration(ctx, live)
+			if err != nil {
+				t.Fatalf("failed to push live shards: %v", err)
+			}
+			getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+
+			sm.params.DryRun = true
+			desired, shards, err := sm.previewShardConfiguration(ctx, c.assignment)
+			if err != nil {
+				t.Fatalf("unexpected error while previewing shard configuration: %v", err)
+			}
+			if diffs := DiffShards(desired, shards, testOperatorIdentityLabel); len(diffs) != len(c.expectedChanges) {
+				t.Errorf("expected desired shards to differ from live shards by %d changes, got %+v", len(c.expectedChanges), diffs)
+			}
+			status := sm.GetDryRunStatus()
+			if !status.Enabled {
+				t.Errorf("expected dry run to be reported as enabled")
+			}
+			if !cmp.Equal(status.Changes, c.expectedChanges) {
+				t.Errorf(cmp.Diff(status.Changes, c.expectedChanges))
+			}
+			// live shards are left untouched
+			shards, err = sm.shardHandler.List(ctx)
+			if err != nil {
+				t.Fatalf("failed to list shards: %v", err)
+			}
+			if diffs := DiffShards(controller.BuildShardResources(live, sm.params), shards, testOperatorIdentityLabel); len(diffs) > 0 {
+				t.Errorf("expected live shards to be unchanged, got %+v", diffs)
+			}
+			if reasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder)); len(reasons) > 0 {
+				t.Errorf("expected no events, got %v", reasons)
+			}
+		})
+	}
+}
+
+func TestBulkSyncDryRun(t *testing.T) {
+	ctx := context.Background()
+	live := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster1", "identity1")},
+		"operator2": {getTestCluster("cluster2", "identity2")},
+	}
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	sm.registryClient = &testRegistryClient{
+		clusters:        []registry.ClusterConfig{getTestCluster("cluster1", "identity1"), getTestCluster("cluster2", "identity2")},
+		resourceVersion: "1",
+	}
+	// operator2 missed its heartbeat for longer than the grace period so that its cluster would fail over
+	sm.operatorHandler = &testOperatorHandler{operators: []model.Operator{
+		getTestOperator("operator1", time.Now()),
+		getTestOperator("operator2", time.Now().Add(-time.Minute)),
+	}}
+	auditLogPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := newAuditLog(auditLogPath, 10)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	sm.auditLog = auditLog
+	err = sm.pushShardConfiguration(ctx, live)
+	if err != nil {
+		t.Fatalf("failed to push live shards: %v", err)
+	}
+	getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list shards: %v", err)
+	}
+	sm.owners = getOwnersFromShards(shards, sm.params)
+	sm.migrations = make(map[string]model.Migration)
+
+	metrics := []*testMetric{
+		{name: "operator_failovers_total"},
+		{name: "cross_operator_dependencies"},
+		{name: "rollout_moved_clusters"},
+		{name: "rollout_pending_clusters"},
+		{name: "rollout_paused"},
+	}
+	previousFailovers, previousDependencies := operatorFailoversTotal, crossOperatorDependencies
+	previousMoved, previousPending, previousPaused := rolloutMovedClusters, rolloutPendingClusters, rolloutPaused
+	operatorFailoversTotal, crossOperatorDependencies = metrics[0], metrics[1]
+	rolloutMovedClusters, rolloutPendingClusters, rolloutPaused = metrics[2], metrics[3], metrics[4]
+	t.Cleanup(func() {
+		operatorFailoversTotal, crossOperatorDependencies = previousFailovers, previousDependencies
+		rolloutMovedClusters, rolloutPendingClusters, rolloutPaused = previousMoved, previousPending, previousPaused
+	})
+
+	sm.params.DryRun = true
+	err = sm.bulkSync(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while syncing in dry run mode: %v", err)
+	}
+	if changes := sm.GetDryRunStatus().Changes; len(changes) == 0 {
+		t.Errorf("expected the failover of cluster2 to be reported as skipped change")
+	}
+	expectedOwners := map[string]string{"cluster1": "operator1", "cluster2": "operator2"}
+	if !cmp.Equal(sm.owners, expectedOwners) {
+		t.Errorf(cmp.Diff(sm.owners, expectedOwners))
+	}
+	if len(sm.failedOver) > 0 || len(sm.migrations) > 0 {
+		t.Errorf("expected no failed over or migrating cluster, got %v and %v", sm.failedOver, sm.migrations)
+	}
+	if entries := sm.GetAuditEntries(0); len(entries) > 0 {
+		t.Errorf("expected no audit entry, got %+v", entries)
+	}
+	data, err := os.ReadFile(auditLogPath)
+	if err != nil || len(data) > 0 {
+		t.Errorf("expected audit log sink to be empty, got %q, error %v", data, err)
+	}
+	for _, metric := range metrics {
+		if metric.measurements > 0 {
+			t.Errorf("expected metric %s not to be measured, got %d measurements", metric.name, metric.measurements)
+		}
+	}
+	if reasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder)); len(reasons) > 0 {
+		t.Errorf("expected no events, got %v", reasons)
+	}
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// reasons of kubernetes events recorded by sharding manager
+const (
+	// Normal, on the shard, a shard was created for an operator
+	shardCreatedReason = "ShardCreated"
+	// Normal, on the shard, clusters or identities of a shard changed
+	shardUpdatedReason = "ShardUpdated"
+	// Normal, on the shard, a shard was deleted as it is no longer part of the assignment
+	shardDeletedReason = "ShardDeleted"
+	// Warning, on the shard, a shard could not be created, updated or deleted
+	shardSyncFailedReason = "ShardSyncFailed"
+	// Normal, on the sharding manager pod, clusters were moved between operators
+	rebalancedReason = "Rebalanced"
+	// Warning, on the sharding manager pod, configuration could not be loaded from registry
+	registrySyncFailedReason = "RegistrySyncFailed"
+	// Warning, on the sharding manager pod, clusters were left unassigned as no operator of their pool is available
+	clustersUnassignedReason = "ClustersUnassigned"
+	// Warning, on the sharding manager pod, registry configuration removing too many clusters was rejected
+	registryChangeRejectedReason = "RegistryChangeRejected"
+	// Normal, on the sharding manager pod, rejected registry configuration was applied after it was accepted
+	registryChangeAcceptedReason = "RegistryChangeAccepted"
+	// Warning, on the operator lease, clusters were moved away from an operator which missed its heartbeat
+	operatorFailoverReason = "OperatorFailover"
+	// Normal, on the operator lease, clusters were moved back to a recovered operator
+	operatorFailbackReason = "OperatorFailback"
+	// Warning, on the overrides configmap, a placement override was rejected
+	overrideRejectedReason = "OverrideRejected"
+	// Warning, on the overrides configmap, placement overrides could not be parsed
+	overridesInvalidReason = "OverridesInvalid"
+	// Normal, on the sharding manager pod, a cluster was removed from its previous operator once its new operator acknowledged it
+	handoffCompletedReason = "HandoffCompleted"
+	// Warning, on the sharding manager pod, a cluster was removed from its previous operator as its new operator did not acknowledge it in time
+	handoffTimedOutReason = "HandoffTimedOut"
+	// Warning, on the sharding manager pod, rollout of cluster moves was paused as operators report errors
+	rolloutPausedReason = "RolloutPaused"
+	// Normal, on the sharding manager pod, rollout of cluster moves was resumed as operators no longer report errors
+	rolloutResumedReason = "RolloutResumed"
+	// Normal, on the revision history configmap, the assignment was rolled back to a previous revision
+	rolledBackReason = "RolledBack"
+	// Normal, on the revision history configmap, rebalancing frozen by a rollback was resumed
+	rebalancingResumedReason = "RebalancingResumed"
+)
+
+// records a kubernetes event when an event recorder is configured and the involved object is known
+func (sm *shardingManager) recordEvent(reference *coreV1.ObjectReference, eventType string, reason string, message string) {
+	if sm.eventRecorder == nil || reference == nil {
+		return
+	}
+	sm.eventRecorder.Event(reference, eventType, reason, message)
+}
+
+func shardReference(shard *typeV1.Shard) *coreV1.ObjectReference {
+	return &coreV1.ObjectReference{
+		APIVersion:      typeV1.SchemeGroupVersion.String(),
+		Kind:            controller.ShardKind,
+		Namespace:       shard.Namespace,
+		Name:            shard.Name,
+		UID:             shard.UID,
+		ResourceVersion: shard.ResourceVersion,
+	}
+}
+
+// pod sharding manager runs in, exposed through the downward api. Events which do not relate to a
+// shard, operator or override are not recorded when sharding manager does not run in a pod
+func getManagerReference() *coreV1.ObjectReference {
+	name, namespace := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE")
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return &coreV1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// records clusters which moved between operators, clusters which were not assigned before are not reported
+func (sm *shardingManager) reportRebalance(previous map[string]string, current map[string]string) {
+	var moves []string
+	for cluster, operator := range current {
+		previousOperator, ok := previous[cluster]
+		if ok && previousOperator != operator {
+			moves = append(moves, fmt.Sprintf("%s from %s to %s", cluster, previousOperator, operator))
+		}
+	}
+	if len(moves) == 0 {
+		return
+	}
+	sort.Strings(moves)
+	sm.recordEvent(sm.reference, coreV1.EventTypeNormal, rebalancedReason,
+		fmt.Sprintf("moved %d clusters between operators: %s", len(moves), strings.Join(moves, ", ")))
+}
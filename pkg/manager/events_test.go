@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// reason of every event recorded so far, in order
+func getRecordedReasons(recorder *record.FakeRecorder) []string {
+	var reasons []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			reasons = append(reasons, strings.Fields(event)[1])
+		default:
+			return reasons
+		}
+	}
+}
+
+func TestPushShardConfigurationEvents(t *testing.T) {
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	recorder := sm.eventRecorder.(*record.FakeRecorder)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name            string
+		assignment      model.ShardAssignment
+		expectedReasons []string
+	}{
+		{
+			name: "Given operators without shards, " +
+				"When shard configuration is pushed, " +
+				"Then a created event should be recorded for every shard",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1")},
+				"operator2": {getTestCluster("cluster2", "identity2")},
+			},
+			expectedReasons: []string{shardCreatedReason, shardCreatedReason},
+		},
+		{
+			name: "Given a shard whose identities changed, " +
+				"When shard configuration is pushed, " +
+				"Then an updated event should only be recorded for the changed shard",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1", "identity3")},
+				"operator2": {getTestCluster("cluster2", "identity2")},
+			},
+			expectedReasons: []string{shardUpdatedReason},
+		},
+		{
+			name: "Given an operator which is no longer part of the assignment, " +
+				"When shard configuration is pushed, " +
+				"Then a deleted event should be recorded for its shard",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1", "identity3")},
+			},
+			expectedReasons: []string{shardDeletedReason},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			err := sm.pushShardConfiguration(ctx, c.assignment)
+			if err != nil {
+				t.Fatalf("unexpected error while pushing shard configuration: %v", err)
+			}
+			actualReasons := getRecordedReasons(recorder)
+			if !cmp.Equal(actualReasons, c.expectedReasons) {
+				t.Errorf(cmp.Diff(actualReasons, c.expectedReasons))
+			}
+		})
+	}
+}
+
+func TestReportRebalance(t *testing.T) {
+	testCases := []struct {
+		name            string
+		previous        map[string]string
+		current         map[string]string
+		expectedReasons []string
+	}{
+		{
+			name: "Given clusters which moved between operators, " +
+				"When rebalance is reported, " +
+				"Then a rebalanced event should be recorded",
+			previous:        map[string]string{"cluster1": "operator1", "cluster2": "operator1"},
+			current:         map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+			expectedReasons: []string{rebalancedReason},
+		},
+		{
+			name: "Given newly assigned clusters only, " +
+				"When rebalance is reported, " +
+				"Then no event should be recorded",
+			previous: map[string]string{"cluster1": "operator1"},
+			current:  map[string]string{"cluster1": "operator1", "cluster2": "operator2"},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+			sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+			sm.reportRebalance(c.previous, c.current)
+			actualReasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+			if !cmp.Equal(actualReasons, c.expectedReasons) {
+				t.Errorf(cmp.Diff(actualReasons, c.expectedReasons))
+			}
+		})
+	}
+}
+
+func TestRegistrySyncFailureEvent(t *testing.T) {
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+	sm.registryClient = registry.NewRegistryClient()
+	sm.identity = "non-existing-shard-identity"
+	err := sm.bulkSync(context.Background())
+	if err == nil {
+		t.Fatalf("expected error while syncing from unavailable registry")
+	}
+	actualReasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+	if !cmp.Equal(actualReasons, []string{registrySyncFailedReason}) {
+		t.Errorf(cmp.Diff(actualReasons, []string{registrySyncFailedReason}))
+	}
+}
@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+var (
+	shardingManagerMeter   = monitoring.NewMeter("admiral_sharding_manager")
+	operatorFailoversTotal = monitoring.NewCounter(
+		"operator_failovers_total",
+		"total number of times clusters were moved between operators due to operator health",
+		monitoring.WithMeter(shardingManagerMeter))
+)
+
+type operatorHealth int
+
+const (
+	// operator heartbeat is current
+	operatorHealthy operatorHealth = iota
+	// operator missed its heartbeat but is still within the grace period, it keeps its clusters
+	// but no new clusters are assigned to it
+	operatorUnresponsive
+	// operator missed its heartbeat for longer than the grace period, its clusters are reassigned
+	operatorFailed
+)
+
+func getOperatorHealth(operator model.Operator, now time.Time, gracePeriod time.Duration) operatorHealth {
+	expiry := operator.LastHeartbeat.Add(operator.LeaseDuration)
+	if !now.After(expiry) {
+		return operatorHealthy
+	}
+	if !now.After(expiry.Add(gracePeriod)) {
+		return operatorUnresponsive
+	}
+	return operatorFailed
+}
+
+// health of discovered operators keyed by operator identity
+func getOperatorsHealth(operators []model.Operator, now time.Time, gracePeriod time.Duration) map[string]operatorHealth {
+	health := make(map[string]operatorHealth)
+	for _, operator := range operators {
+		health[operator.Identity] = getOperatorHealth(operator, now, gracePeriod)
+	}
+	return health
+}
+
+// clusters moved between operators because of a failover or failback
+type failoverRecord struct {
+	reason   string
+	operator model.Operator
+	clusters []string
+}
+
+// state of an operator health reconciliation, applied to the sharding manager once the resulting
+// assignment has been pushed
+type healthReconciliation struct {
+	// clusters which stay with their current operator
+	current map[string]string
+	// healthy operators which are neither cordoned nor draining and can accept new clusters
+	available []model.Operator
+	// operator each failed over cluster was moved away from
+	failedOver map[string]string
+	// failovers to report once the assignment is pushed
+	records []failoverRecord
+	// outcome of applying placement overrides
+	overrideStatus model.OverrideStatus
+	// audit trigger of each cluster moved for a specific reason, other clusters are moved by the bulk sync
+	triggers map[string]string
+	// clusters left unassigned as no operator of their pool is available
+	unassigned []string
+}
+
+// determines which clusters keep their current operator based on the health of discovered operators
+func (sm *shardingManager) reconcileOperatorHealth(
+	clusters []registry.ClusterConfig,
+	operators []model.Operator,
+	now time.Time) healthReconciliation {
+	var (
+		current    = make(map[string]string)
+		available  []model.Operator
+		records    []failoverRecord
+		health     = getOperatorsHealth(operators, now, sm.params.OperatorGracePeriod)
+		byIdentity = make(map[string]model.Operator)
+		moved      = make(map[string]string)
+		failedOver = make(map[string][]string)
+		failedBack = make(map[string][]string)
+	)
+	for _, operator := range operators {
+		byIdentity[operator.Identity] = operator
+		if health[operator.Identity] == operatorHealthy && isSchedulable(operator) {
+			available = append(available, operator)
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, cluster := range clusters {
+		registered[getClusterKey(cluster)] = true
+	}
+	// clusters are no longer failed back to an operator which is not discovered anymore
+	for name, home := range sm.failedOver {
+		if _, discovered := health[home]; !discovered {
+			logrus.Infof("operator %s is no longer discovered, cluster %s will not fail back to it", home, name)
+			continue
+		}
+		if registered[name] {
+			moved[name] = home
+		}
+	}
+
+	for _, cluster := range clusters {
+		key := getClusterKey(cluster)
+		owner, ok := sm.owners[key]
+		if !ok {
+			continue
+		}
+		// failback is deferred while the recovered operator is cordoned or draining
+		home, ok := moved[key]
+		if state, discovered := health[home]; ok && discovered && state == operatorHealthy && isSchedulable(byIdentity[home]) {
+			delete(moved, key)
+			if sm.params.FailoverRecoveryPolicy == model.FailbackRecoveryPolicy && home != owner {
+				failedBack[home] = append(failedBack[home], key)
+				current[key] = home
+				continue
+			}
+		}
+		state, discovered := health[owner]
+		if !discovered {
+			logrus.Warnf("operator %s is no longer discovered, reassigning cluster %s", owner, key)
+			continue
+		}
+		if state == operatorFailed {
+			if _, ok := moved[key]; !ok {
+				moved[key] = owner
+			}
+			failedOver[owner] = append(failedOver[owner], key)
+			continue
+		}
+		current[key] = owner
+	}
+
+	for identity, names := range failedOver {
+		records = append(records, failoverRecord{reason: operatorFailoverReason, operator: byIdentity[identity], clusters: names})
+	}
+	for identity, names := range failedBack {
+		records = append(records, failoverRecord{reason: operatorFailbackReason, operator: byIdentity[identity], clusters: names})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].operator.Identity != records[j].operator.Identity {
+			return records[i].operator.Identity < records[j].operator.Identity
+		}
+		return records[i].reason < records[j].reason
+	})
+	return healthReconciliation{
+		current:    current,
+		available:  available,
+		failedOver: moved,
+		records:    records,
+		triggers:   make(map[string]string),
+	}
+}
+
+// records each failover as a kubernetes event on the operator lease and as a metric
+func (sm *shardingManager) reportFailovers(records []failoverRecord) {
+	for _, record := range records {
+		eventType := coreV1.EventTypeNormal
+		message := fmt.Sprintf("moved %d clusters back to recovered operator %s: %v",
+			len(record.clusters), record.operator.Identity, record.clusters)
+		if record.reason == operatorFailoverReason {
+			eventType = coreV1.EventTypeWarning
+			message = fmt.Sprintf("moved %d clusters away from operator %s after it missed its heartbeat for longer than %s: %v",
+				len(record.clusters), record.operator.Identity, sm.params.OperatorGracePeriod, record.clusters)
+		}
+		logrus.Warn(message)
+		sm.recordEvent(operatorReference(record.operator), eventType, record.reason, message)
+		operatorFailoversTotal.Increment(api.WithAttributes(
+			attribute.Key("operator").String(record.operator.Identity),
+			attribute.Key("reason").String(record.reason),
+		))
+	}
+}
+
+func operatorReference(operator model.Operator) *coreV1.ObjectReference {
+	return &coreV1.ObjectReference{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Namespace:  operator.Namespace,
+		Name:       operator.LeaseName,
+	}
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"k8s.io/client-go/tools/record"
+)
+
+var (
+	testNow      = time.Date(2024, 6, 20, 18, 25, 0, 0, time.UTC)
+	testClusters = []registry.ClusterConfig{
+		{Name: "cluster1", Locality: "us-west-2"},
+		{Name: "cluster2", Locality: "us-east-2"},
+		{Name: "cluster3", Locality: "us-west-2"},
+	}
+)
+
+func getTestOperator(identity string, lastHeartbeat time.Time) model.Operator {
+	return model.Operator{
+		Identity:      identity,
+		Namespace:     "shard-namespace",
+		LeaseName:     identity,
+		LastHeartbeat: lastHeartbeat,
+		LeaseDuration: 10 * time.Second,
+	}
+}
+
+func getTestShardingManager(policy string, owners map[string]string, failedOver map[string]string) *shardingManager {
+	return &shardingManager{
+		cache: model.ShardingMangerCache{
+			ClusterCache: testClusters,
+		},
+		loadDistributor: NewLeastLoadedDistributor(),
+		eventRecorder:   record.NewFakeRecorder(10),
+		params: &model.ShardingManagerParams{
+			OperatorGracePeriod:    30 * time.Second,
+			FailoverRecoveryPolicy: policy,
+		},
+		owners:     owners,
+		failedOver: failedOver,
+	}
+}
+
+func TestDeriveShardConfigurationWithOperatorHealth(t *testing.T) {
+	healthy := testNow.Add(-5 * time.Second)
+	unresponsive := testNow.Add(-20 * time.Second)
+	failed := testNow.Add(-time.Minute)
+
+	testCases := []struct {
+		name               string
+		sm                 *shardingManager
+		operators          []model.Operator
+		expectedOwners     map[string]string
+		expectedFailedOver map[string]string
+		expectedReasons    []string
+	}{
+		{
+			name: "Given healthy operators and no existing assignment, " +
+				"When shard configuration is derived, " +
+				"Then clusters should be distributed amongst operators",
+			sm:                 getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{}),
+			operators:          []model.Operator{getTestOperator("operator1", healthy), getTestOperator("operator2", healthy)},
+			expectedOwners:     map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator1"},
+			expectedFailedOver: map[string]string{},
+		},
+		{
+			name: "Given an operator which missed its heartbeat within the grace period, " +
+				"When shard configuration is derived, " +
+				"Then it should keep its clusters",
+			sm: getTestShardingManager(model.FailbackRecoveryPolicy,
+				map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator1"}, map[string]string{}),
+			operators:          []model.Operator{getTestOperator("operator1", unresponsive), getTestOperator("operator2", healthy)},
+			expectedOwners:     map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator1"},
+			expectedFailedOver: map[string]string{},
+		},
+		{
+			name: "Given an operator which missed its heartbeat for longer than the grace period, " +
+				"When shard configuration is derived, " +
+				"Then its clusters should fail over to healthy operators",
+			sm: getTestShardingManager(model.FailbackRecoveryPolicy,
+				map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator1"}, map[string]string{}),
+			operators:          []model.Operator{getTestOperator("operator1", failed), getTestOperator("operator2", healthy)},
+			expectedOwners:     map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"},
+			expectedFailedOver: map[string]string{"cluster1": "operator1", "cluster3": "operator1"},
+			expectedReasons:    []string{operatorFailoverReason},
+		},
+		{
+			name: "Given a recovered operator and failback recovery policy, " +
+				"When shard configuration is derived, " +
+				"Then failed over clusters should move back to it",
+			sm: getTestShardingManager(model.FailbackRecoveryPolicy,
+				map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"},
+				map[string]string{"cluster1": "operator1", "cluster3": "operator1"}),
+			operators:          []model.Operator{getTestOperator("operator1", healthy), getTestOperator("operator2", healthy)},
+			expectedOwners:     map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator1"},
+			expectedFailedOver: map[string]string{},
+			expectedReasons:    []string{operatorFailbackReason},
+		},
+		{
+			name: "Given a recovered operator and stay recovery policy, " +
+				"When shard configuration is derived, " +
+				"Then failed over clusters should stay with their current operator",
+			sm: getTestShardingManager(model.StayRecoveryPolicy,
+				map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"},
+				map[string]string{"cluster1": "operator1", "cluster3": "operator1"}),
+			operators:          []model.Operator{getTestOperator("operator1", healthy), getTestOperator("operator2", healthy)},
+			expectedOwners:     map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"},
+			expectedFailedOver: map[string]string{},
+		},
+		{
+			name: "Given an operator whose lease is gone while its clusters are failed over, " +
+				"When shard configuration is derived, " +
+				"Then failed over clusters should stay with their current operator and no longer fail back to it",
+			sm: getTestShardingManager(model.FailbackRecoveryPolicy,
+				map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"},
+				map[string]string{"cluster1": "operator9", "cluster3": "operator9"}),
+			operators:          []model.Operator{getTestOperator("operator2", healthy)},
+			expectedOwners:     map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"},
+			expectedFailedOver: map[string]string{},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, reconciliation, err := c.sm.deriveShardConfiguration(c.operators, testNow)
+			if err != nil {
+				t.Fatalf("unexpected error while deriving shard configuration: %v", err)
+			}
+			for _, operator := range c.operators {
+				if _, ok := assignment[operator.Identity]; !ok {
+					t.Errorf("expected operator %s to be part of the assignment", operator.Identity)
+				}
+			}
+			actualOwners := getOwners(assignment)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+			if !cmp.Equal(reconciliation.failedOver, c.expectedFailedOver) {
+				t.Errorf(cmp.Diff(reconciliation.failedOver, c.expectedFailedOver))
+			}
+			var actualReasons []string
+			for _, record := range reconciliation.records {
+				actualReasons = append(actualReasons, record.reason)
+			}
+			if !cmp.Equal(actualReasons, c.expectedReasons) {
+				t.Errorf(cmp.Diff(actualReasons, c.expectedReasons))
+			}
+		})
+	}
+}
+
+func TestReportFailovers(t *testing.T) {
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	recorder := sm.eventRecorder.(*record.FakeRecorder)
+	sm.reportFailovers([]failoverRecord{{
+		reason:   operatorFailoverReason,
+		operator: getTestOperator("operator1", testNow),
+		clusters: []string{"cluster1"},
+	}})
+	select {
+	case event := <-recorder.Events:
+		expectedPrefix := "Warning " + operatorFailoverReason
+		if !strings.HasPrefix(event, expectedPrefix) {
+			t.Errorf("expected event with prefix %q, got %q", expectedPrefix, event)
+		}
+	default:
+		t.Errorf("expected failover to be recorded as an event")
+	}
+}
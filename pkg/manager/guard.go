@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+	api "go.opentelemetry.io/otel/metric"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+var registryChangesRejectedTotal = monitoring.NewCounter(
+	"registry_changes_rejected_total",
+	"total number of registry configurations rejected for removing too many clusters",
+	monitoring.WithMeter(shardingManagerMeter))
+
+// checks the registry configuration against the last applied one, a configuration which removes more clusters
+// than allowed is rejected so that the last applied configuration is kept, unless its removals were accepted
+// through the admin api. Returns whether the configuration can be applied
+func (sm *shardingManager) guardRegistryChange(cache []registry.ClusterConfig, resourceVersion string, now time.Time) bool {
+	previous := sm.cache.ClusterCache
+	removed := getRemovedClusters(previous, cache)
+	if !exceedsRemovalLimits(len(removed), len(previous), sm.params) {
+		sm.rejectedChange, sm.acceptedRemovals = nil, nil
+		return true
+	}
+	if isAccepted(removed, sm.acceptedRemovals) {
+		message := fmt.Sprintf("applying accepted registry configuration which removes %d of %d clusters: %v", len(removed), len(previous), removed)
+		logrus.Warn(message)
+		sm.recordEvent(sm.reference, coreV1.EventTypeNormal, registryChangeAcceptedReason, message)
+		sm.rejectedChange, sm.acceptedRemovals = nil, nil
+		return true
+	}
+
+	message := fmt.Sprintf("rejected registry configuration which removes %d of %d clusters, keeping last applied configuration: %v",
+		len(removed), len(previous), removed)
+	logrus.Error(message)
+	// a rejection is only reported once as long as the same clusters are removed
+	if sm.rejectedChange == nil || !slices.Equal(sm.rejectedChange.Removed, removed) {
+		sm.recordEvent(sm.reference, coreV1.EventTypeWarning, registryChangeRejectedReason, message)
+		registryChangesRejectedTotal.Increment(api.WithAttributes())
+	}
+	sm.rejectedChange = &model.RegistryChange{
+		Time:             now,
+		ResourceVersion:  resourceVersion,
+		PreviousClusters: len(previous),
+		Clusters:         len(cache),
+		Removed:          removed,
+	}
+	return false
+}
+
+// sorted names of clusters which are part of the previous configuration only
+func getRemovedClusters(previous []registry.ClusterConfig, current []registry.ClusterConfig) []string {
+	names := make(map[string]bool)
+	for _, cluster := range current {
+		names[cluster.Name] = true
+	}
+	removed := []string{}
+	for _, cluster := range previous {
+		if !names[cluster.Name] {
+			removed = append(removed, cluster.Name)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// limits are not applied when no configuration was applied before nor is held by the live shards
+func exceedsRemovalLimits(removed int, previous int, params *model.ShardingManagerParams) bool {
+	if previous == 0 || removed == 0 {
+		return false
+	}
+	return (params.MaxRegistryRemovals > 0 && removed > params.MaxRegistryRemovals) ||
+		(params.MaxRegistryRemovalPercent > 0 && float64(removed)*100/float64(previous) > params.MaxRegistryRemovalPercent)
+}
+
+func isAccepted(removed []string, accepted map[string]bool) bool {
+	if len(accepted) == 0 {
+		return false
+	}
+	for _, cluster := range removed {
+		if !accepted[cluster] {
+			return false
+		}
+	}
+	return true
+}
+
+// accepts removals of the currently rejected registry configuration, they are applied by the next sync
+func (sm *shardingManager) AcceptRegistryChange() (model.RegistryChange, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.rejectedChange == nil {
+		return model.RegistryChange{}, fmt.Errorf("no registry configuration is rejected")
+	}
+	sm.acceptedRemovals = make(map[string]bool)
+	for _, cluster := range sm.rejectedChange.Removed {
+		sm.acceptedRemovals[cluster] = true
+	}
+	logrus.Warnf("accepted removal of %d clusters from registry configuration: %v", len(sm.rejectedChange.Removed), sm.rejectedChange.Removed)
+	return *sm.rejectedChange, nil
+}
+
+// registry configuration currently rejected along with removals accepted through the admin api
+func (sm *shardingManager) GetRegistryGuardStatus() model.RegistryGuardStatus {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	status := model.RegistryGuardStatus{Accepted: []string{}}
+	if sm.rejectedChange != nil {
+		rejected := *sm.rejectedChange
+		status.Rejected = &rejected
+	}
+	for cluster := range sm.acceptedRemovals {
+		status.Accepted = append(status.Accepted, cluster)
+	}
+	sort.Strings(status.Accepted)
+	return status
+}
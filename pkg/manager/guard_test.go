@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGuardRegistryChange(t *testing.T) {
+	previous := []registry.ClusterConfig{
+		getTestCluster("cluster1"), getTestCluster("cluster2"), getTestCluster("cluster3"), getTestCluster("cluster4"),
+	}
+	testCases := []struct {
+		name             string
+		cache            []registry.ClusterConfig
+		maxPercent       float64
+		maxRemovals      int
+		rejected         *model.RegistryChange
+		accepted         map[string]bool
+		expectedApplied  bool
+		expectedRejected []string
+		expectedReasons  []string
+	}{
+		{
+			name: "Given a registry configuration removing clusters within the limits, " +
+				"When the change is guarded, " +
+				"Then it should be applied",
+			cache:           previous[1:],
+			maxPercent:      50,
+			expectedApplied: true,
+		},
+		{
+			name: "Given a registry configuration removing more clusters than the percentage allowed, " +
+				"When the change is guarded, " +
+				"Then it should be rejected",
+			cache:            previous[3:],
+			maxPercent:       50,
+			expectedRejected: []string{"cluster1", "cluster2", "cluster3"},
+			expectedReasons:  []string{registryChangeRejectedReason},
+		},
+		{
+			name: "Given a registry configuration removing more clusters than the number allowed, " +
+				"When the change is guarded, " +
+				"Then it should be rejected",
+			cache:            previous[2:],
+			maxRemovals:      1,
+			expectedRejected: []string{"cluster1", "cluster2"},
+			expectedReasons:  []string{registryChangeRejectedReason},
+		},
+		{
+			name: "Given a registry configuration which was already rejected, " +
+				"When the change is guarded again, " +
+				"Then it should be rejected without reporting it again",
+			cache:            previous[2:],
+			maxRemovals:      1,
+			rejected:         &model.RegistryChange{Removed: []string{"cluster1", "cluster2"}},
+			expectedRejected: []string{"cluster1", "cluster2"},
+		},
+		{
+			name: "Given a rejected registry configuration which was accepted, " +
+				"When the change is guarded, " +
+				"Then it should be applied",
+			cache:           previous[2:],
+			maxRemovals:     1,
+			rejected:        &model.RegistryChange{Removed: []string{"cluster1", "cluster2"}},
+			accepted:        map[string]bool{"cluster1": true, "cluster2": true},
+			expectedApplied: true,
+			expectedReasons: []string{registryChangeAcceptedReason},
+		},
+		{
+			name: "Given an accepted registry configuration, " +
+				"When a configuration removing other clusters is guarded, " +
+				"Then it should be rejected",
+			cache:            previous[:1],
+			maxRemovals:      1,
+			rejected:         &model.RegistryChange{Removed: []string{"cluster1", "cluster2"}},
+			accepted:         map[string]bool{"cluster1": true, "cluster2": true},
+			expectedRejected: []string{"cluster2", "cluster3", "cluster4"},
+			expectedReasons:  []string{registryChangeRejectedReason},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+			sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+			sm.params.MaxRegistryRemovalPercent = c.maxPercent
+			sm.params.MaxRegistryRemovals = c.maxRemovals
+			sm.cache.ClusterCache = previous
+			sm.rejectedChange = c.rejected
+			sm.acceptedRemovals = c.accepted
+
+			applied := sm.guardRegistryChange(c.cache, "2", testNow)
+			if applied != c.expectedApplied {
+				t.Errorf("expected applied to be %v, got %v", c.expectedApplied, applied)
+			}
+			var actualRejected []string
+			if sm.rejectedChange != nil {
+				actualRejected = sm.rejectedChange.Removed
+			}
+			if !cmp.Equal(actualRejected, c.expectedRejected) {
+				t.Errorf(cmp.Diff(actualRejected, c.expectedRejected))
+			}
+			actualReasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+			if !cmp.Equal(actualReasons, c.expectedReasons) {
+				t.Errorf(cmp.Diff(actualReasons, c.expectedReasons))
+			}
+		})
+	}
+}
+
+func TestAcceptRegistryChange(t *testing.T) {
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	if _, err := sm.AcceptRegistryChange(); err == nil {
+		t.Errorf("expected an error when no registry configuration is rejected")
+	}
+	sm.rejectedChange = &model.RegistryChange{Removed: []string{"cluster1", "cluster2"}}
+	if _, err := sm.AcceptRegistryChange(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	expected := model.RegistryGuardStatus{Rejected: sm.rejectedChange, Accepted: []string{"cluster1", "cluster2"}}
+	actual := sm.GetRegistryGuardStatus()
+	if !cmp.Equal(actual, expected) {
+		t.Errorf(cmp.Diff(actual, expected))
+	}
+}
+
+func TestGuardRegistryChangeAfterRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	live := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster1", "identity1"), getTestCluster("cluster2", "identity2")},
+		"operator2": {getTestCluster("cluster3", "identity3"), getTestCluster("cluster4", "identity4")},
+	}
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.params.MaxRegistryRemovalPercent = 50
+	sm.params.SyncPeriod = time.Hour
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	sm.registryClient = &testRegistryClient{clusters: []registry.ClusterConfig{getTestCluster("cluster4", "identity4")}, resourceVersion: "2"}
+	sm.operatorHandler = &testOperatorHandler{operators: []model.Operator{
+		getTestOperator("operator1", time.Now()), getTestOperator("operator2", time.Now()),
+	}}
+	sm.migrations = make(map[string]model.Migration)
+	err := sm.pushShardConfiguration(ctx, live)
+	if err != nil {
+		t.Fatalf("failed to push live shards: %v", err)
+	}
+	// a restarted sharding manager has not applied any registry configuration yet
+	sm.cache.ClusterCache = []registry.ClusterConfig{}
+
+	err = sm.Start(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while starting: %v", err)
+	}
+	status := sm.GetRegistryGuardStatus()
+	expectedRejected := []string{"cluster1", "cluster2", "cluster3"}
+	if status.Rejected == nil || !cmp.Equal(status.Rejected.Removed, expectedRejected) {
+		t.Errorf("expected removal of %v to be rejected, got %+v", expectedRejected, status.Rejected)
+	}
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list shards: %v", err)
+	}
+	if diffs := DiffShards(controller.BuildShardResources(live, sm.params), shards, testOperatorIdentityLabel); len(diffs) > 0 {
+		t.Errorf("expected live shards to be kept, got %+v", diffs)
+	}
+}
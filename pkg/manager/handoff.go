@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// outcomes of a handoff reported as metric
+const (
+	acknowledgedHandoffResult = "acknowledged"
+	timedOutHandoffResult     = "timeout"
+	cancelledHandoffResult    = "cancelled"
+)
+
+var handoffsTotal = monitoring.NewCounter(
+	"handoffs_total",
+	"total number of clusters handed off between operators by outcome",
+	monitoring.WithMeter(shardingManagerMeter))
+
+// starts a handoff for every cluster moved away from an operator which can still handle it and completes
+// handoffs which were acknowledged by the new operator or timed out. Returns the assignment to push, in
+// which clusters being handed off are kept by their previous operator as well, along with the handoffs
+// still in flight. Clusters are moved at once when no handoff timeout is configured or when shards are
+// rendered to a directory, as rendered shards never report the sync status acknowledging a handoff
+func (sm *shardingManager) reconcileHandoffs(
+	ctx context.Context,
+	assignment model.ShardAssignment,
+	triggers map[string]string,
+	operators []model.Operator,
+	now time.Time) (model.ShardAssignment, map[string]model.Migration) {
+	migrations := make(map[string]model.Migration)
+	if sm.params.HandoffTimeout <= 0 || sm.params.OutputDir != "" {
+		return assignment, migrations
+	}
+	var (
+		owners   = make(map[string]string)
+		clusters = make(map[string]registry.ClusterConfig)
+		health   = getOperatorsHealth(operators, now, sm.params.OperatorGracePeriod)
+	)
+	for operatorIdentity, assigned := range assignment {
+		for _, cluster := range assigned {
+			owners[getClusterKey(cluster)] = operatorIdentity
+			clusters[getClusterKey(cluster)] = cluster
+		}
+	}
+	for key, to := range owners {
+		migration, inFlight := sm.migrations[key]
+		switch {
+		case inFlight && migration.To == to:
+		case inFlight && migration.From == to:
+			logrus.Infof("cancelled handoff of cluster %s as it moved back to operator %s", key, to)
+			handoffsTotal.Increment(api.WithAttributes(attribute.Key("result").String(cancelledHandoffResult)))
+			continue
+		case inFlight:
+			migration.To = to
+			migration.Started = now
+			migration.LastSynced = nil
+		default:
+			from, ok := sm.owners[key]
+			// clusters which failed over are moved at once as their previous operator cannot handle them anymore
+			if !ok || from == to || triggers[key] == model.FailoverAuditTrigger {
+				continue
+			}
+			migration = model.Migration{Cluster: key, From: from, To: to, Started: now}
+		}
+		if state, discovered := health[migration.From]; !discovered || state == operatorFailed {
+			continue
+		}
+		migration.Deadline = migration.Started.Add(sm.params.HandoffTimeout)
+		migrations[key] = migration
+	}
+	if len(migrations) == 0 {
+		return assignment, migrations
+	}
+
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		logrus.Errorf("failed to list shards to check handoff acknowledgements: %v", err)
+	}
+	for key, migration := range migrations {
+		if err == nil && migration.LastSynced == nil {
+			// syncs the new operator completed before the handoff started cannot acknowledge it
+			lastSynced := getLastSynced(shards, migration.To, sm.params.OperatorIdentityLabel)
+			migration.LastSynced = &lastSynced
+			migrations[key] = migration
+		} else if err == nil && isHandoffAcknowledged(shards, migration, sm.params) {
+			sm.completeHandoff(acknowledgedHandoffResult, coreV1.EventTypeNormal, handoffCompletedReason,
+				fmt.Sprintf("operator %s acknowledged cluster %s, removed it from operator %s", migration.To, key, migration.From))
+			delete(migrations, key)
+			continue
+		}
+		if !now.Before(migration.Deadline) {
+			sm.completeHandoff(timedOutHandoffResult, coreV1.EventTypeWarning, handoffTimedOutReason,
+				fmt.Sprintf("operator %s did not acknowledge cluster %s within %s, removed it from operator %s",
+					migration.To, key, sm.params.HandoffTimeout, migration.From))
+			delete(migrations, key)
+		}
+	}
+
+	pushed := make(model.ShardAssignment)
+	for operatorIdentity, clusters := range assignment {
+		pushed[operatorIdentity] = append([]registry.ClusterConfig{}, clusters...)
+	}
+	for key, migration := range migrations {
+		pushed[migration.From] = append(pushed[migration.From], clusters[key])
+	}
+	return pushed, migrations
+}
+
+func (sm *shardingManager) completeHandoff(result string, eventType string, reason string, message string) {
+	logrus.Info(message)
+	if sm.params.DryRun {
+		return
+	}
+	sm.recordEvent(sm.reference, eventType, reason, message)
+	handoffsTotal.Increment(api.WithAttributes(attribute.Key("result").String(result)))
+}
+
+// a handoff is acknowledged once a shard of the new operator holding the cluster, or the part of the cluster
+// handed off, completed a sync after the last sync the operator reported when the handoff started. Both
+// times are taken from the clock of the operator so that a skewed clock cannot acknowledge a handoff early
+func isHandoffAcknowledged(shards []typeV1.Shard, migration model.Migration, params *model.ShardingManagerParams) bool {
+	for _, shard := range shards {
+		if shard.Labels[params.OperatorIdentityLabel] != migration.To ||
+			getOwnersFromShards([]typeV1.Shard{shard}, params)[migration.Cluster] != migration.To {
+			continue
+		}
+		if synced, ok := getSyncCompleted(shard); ok && synced.After(*migration.LastSynced) {
+			return true
+		}
+	}
+	return false
+}
+
+// latest sync completed by any shard of the operator, zero when the operator has not completed any sync
+func getLastSynced(shards []typeV1.Shard, operatorIdentity string, operatorIdentityLabel string) time.Time {
+	var lastSynced time.Time
+	for _, shard := range shards {
+		if shard.Labels[operatorIdentityLabel] != operatorIdentity {
+			continue
+		}
+		if synced, ok := getSyncCompleted(shard); ok && synced.After(lastSynced) {
+			lastSynced = synced
+		}
+	}
+	return lastSynced
+}
+
+func getSyncCompleted(shard typeV1.Shard) (time.Time, bool) {
+	for _, condition := range shard.Status.Conditions {
+		if condition.Type == typeV1.SyncComplete &&
+			condition.Status == typeV1.TrueConditionStatus &&
+			condition.Reason == typeV1.Processed {
+			return condition.LastUpdatedTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// clusters currently handed off between operators, sorted by cluster
+func (sm *shardingManager) GetMigrations() []model.Migration {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	migrations := []model.Migration{}
+	for _, migration := range sm.migrations {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Cluster < migrations[j].Cluster
+	})
+	return migrations
+}
@@ -0,0 +1,224 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// shard of the operator holding the provided cluster which completed a sync at the provided time
+func getTestAcknowledgedShard(operatorIdentity string, cluster string, synced time.Time) *typeV1.Shard {
+	return &typeV1.Shard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.GetShardName(operatorIdentity, 0),
+			Namespace: "shard-namespace",
+			Labels:    map[string]string{controller.ShardIdentity: "dev", testOperatorIdentityLabel: operatorIdentity},
+		},
+		Spec: typeV1.ShardSpec{Clusters: []typeV1.ClusterShards{{Name: cluster}}},
+		Status: typeV1.ShardStatus{Conditions: []typeV1.ShardStatusCondition{{
+			Type:            typeV1.SyncComplete,
+			Status:          typeV1.TrueConditionStatus,
+			Reason:          typeV1.Processed,
+			LastUpdatedTime: metav1.NewTime(synced),
+		}}},
+	}
+}
+
+func TestReconcileHandoffs(t *testing.T) {
+	assignment := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster2")},
+		"operator2": {getTestCluster("cluster1")},
+	}
+	lastSynced := testNow.Add(-time.Second)
+	inFlight := map[string]model.Migration{
+		"cluster1": {Cluster: "cluster1", From: "operator1", To: "operator2", Started: testNow, Deadline: testNow.Add(time.Minute), LastSynced: &lastSynced},
+	}
+	notListed := map[string]model.Migration{
+		"cluster1": {Cluster: "cluster1", From: "operator1", To: "operator2", Started: testNow, Deadline: testNow.Add(time.Minute)},
+	}
+	testCases := []struct {
+		name               string
+		migrations         map[string]model.Migration
+		shards             []*typeV1.Shard
+		triggers           map[string]string
+		now                time.Time
+		expectedPushed     map[string][]string
+		expectedMigrations []string
+		expectedLastSynced map[string]time.Time
+		expectedReasons    []string
+	}{
+		{
+			name: "Given a cluster moved between healthy operators, " +
+				"When handoffs are reconciled, " +
+				"Then the cluster should be kept by both operators",
+			migrations:         map[string]model.Migration{},
+			now:                testNow,
+			expectedPushed:     map[string][]string{"operator1": {"cluster1", "cluster2"}, "operator2": {"cluster1"}},
+			expectedMigrations: []string{"cluster1"},
+			expectedLastSynced: map[string]time.Time{"cluster1": {}},
+		},
+		{
+			name: "Given a cluster which failed over, " +
+				"When handoffs are reconciled, " +
+				"Then the cluster should be moved at once",
+			migrations:     map[string]model.Migration{},
+			triggers:       map[string]string{"cluster1": model.FailoverAuditTrigger},
+			now:            testNow,
+			expectedPushed: map[string][]string{"operator1": {"cluster2"}, "operator2": {"cluster1"}},
+		},
+		{
+			name: "Given a handoff which was not acknowledged yet, " +
+				"When handoffs are reconciled before the timeout, " +
+				"Then the cluster should still be kept by both operators",
+			migrations:         inFlight,
+			shards:             []*typeV1.Shard{getTestAcknowledgedShard("operator2", "cluster1", testNow.Add(-time.Second))},
+			now:                testNow.Add(30 * time.Second),
+			expectedPushed:     map[string][]string{"operator1": {"cluster1", "cluster2"}, "operator2": {"cluster1"}},
+			expectedMigrations: []string{"cluster1"},
+			expectedLastSynced: map[string]time.Time{"cluster1": lastSynced},
+		},
+		{
+			name: "Given a handoff started before the shards of the new operator could be listed, " +
+				"When handoffs are reconciled, " +
+				"Then the last sync of the new operator should be recorded instead of acknowledging the handoff",
+			migrations:         notListed,
+			shards:             []*typeV1.Shard{getTestAcknowledgedShard("operator2", "cluster1", testNow.Add(10*time.Second))},
+			now:                testNow.Add(30 * time.Second),
+			expectedPushed:     map[string][]string{"operator1": {"cluster1", "cluster2"}, "operator2": {"cluster1"}},
+			expectedMigrations: []string{"cluster1"},
+			expectedLastSynced: map[string]time.Time{"cluster1": testNow.Add(10 * time.Second)},
+		},
+		{
+			name: "Given a handoff acknowledged by the new operator, " +
+				"When handoffs are reconciled, " +
+				"Then the cluster should be removed from the previous operator",
+			migrations:      inFlight,
+			shards:          []*typeV1.Shard{getTestAcknowledgedShard("operator2", "cluster1", testNow.Add(10*time.Second))},
+			now:             testNow.Add(30 * time.Second),
+			expectedPushed:  map[string][]string{"operator1": {"cluster2"}, "operator2": {"cluster1"}},
+			expectedReasons: []string{handoffCompletedReason},
+		},
+		{
+			name: "Given a handoff which was not acknowledged in time, " +
+				"When handoffs are reconciled, " +
+				"Then the cluster should be removed from the previous operator",
+			migrations:      inFlight,
+			now:             testNow.Add(time.Minute),
+			expectedPushed:  map[string][]string{"operator1": {"cluster2"}, "operator2": {"cluster1"}},
+			expectedReasons: []string{handoffTimedOutReason},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			for _, shard := range c.shards {
+				_, err := client.AdmiralV1().Shards(shard.Namespace).Create(context.Background(), shard, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("failed to create shard: %v", err)
+				}
+			}
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{"cluster1": "operator1", "cluster2": "operator1"}, map[string]string{})
+			sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+			sm.params.ShardNamespace = "shard-namespace"
+			sm.params.ShardingManagerIdentity = "dev"
+			sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+			sm.params.HandoffTimeout = time.Minute
+			sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: client.AdmiralV1()}, sm.params)
+			sm.migrations = c.migrations
+			operators := []model.Operator{getTestOperator("operator1", c.now), getTestOperator("operator2", c.now)}
+
+			pushed, migrations := sm.reconcileHandoffs(context.Background(), assignment, c.triggers, operators, c.now)
+			actualPushed := make(map[string][]string)
+			for operatorIdentity, clusters := range pushed {
+				for _, cluster := range clusters {
+					actualPushed[operatorIdentity] = append(actualPushed[operatorIdentity], cluster.Name)
+				}
+				sort.Strings(actualPushed[operatorIdentity])
+			}
+			if !cmp.Equal(actualPushed, c.expectedPushed) {
+				t.Errorf(cmp.Diff(actualPushed, c.expectedPushed))
+			}
+			var actualMigrations []string
+			for key := range migrations {
+				actualMigrations = append(actualMigrations, key)
+			}
+			if !cmp.Equal(actualMigrations, c.expectedMigrations) {
+				t.Errorf(cmp.Diff(actualMigrations, c.expectedMigrations))
+			}
+			for key, expected := range c.expectedLastSynced {
+				if actual := migrations[key].LastSynced; actual == nil || !actual.Equal(expected) {
+					t.Errorf("expected last sync %v of cluster %s, got %v", expected, key, actual)
+				}
+			}
+			actualReasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+			if !cmp.Equal(actualReasons, c.expectedReasons) {
+				t.Errorf(cmp.Diff(actualReasons, c.expectedReasons))
+			}
+		})
+	}
+}
+
+func TestIsHandoffAcknowledged(t *testing.T) {
+	lastSynced := testNow.Add(-10 * time.Minute)
+	migration := model.Migration{Cluster: "cluster1/identity1", From: "operator1", To: "operator2", Started: testNow, LastSynced: &lastSynced}
+	params := &model.ShardingManagerParams{
+		OperatorIdentityLabel: testOperatorIdentityLabel,
+		ShardingGranularity:   model.IdentityShardingGranularity,
+	}
+	withIdentity := func(shard *typeV1.Shard, identity string) typeV1.Shard {
+		shard.Spec.Clusters[0].Identities = []typeV1.IdentityItem{{Name: identity}}
+		return *shard
+	}
+	testCases := []struct {
+		name     string
+		shards   []typeV1.Shard
+		expected bool
+	}{
+		{
+			name: "Given an operator whose clock is behind the clock of the sharding manager, " +
+				"When the operator completed a sync after its last sync before the handoff, " +
+				"Then the handoff should be acknowledged",
+			shards:   []typeV1.Shard{withIdentity(getTestAcknowledgedShard("operator2", "cluster1", testNow.Add(-5*time.Minute)), "identity1")},
+			expected: true,
+		},
+		{
+			name: "Given an operator whose clock is ahead of the clock of the sharding manager, " +
+				"When the operator did not complete a sync after its last sync before the handoff, " +
+				"Then the handoff should not be acknowledged",
+			shards:   []typeV1.Shard{withIdentity(getTestAcknowledgedShard("operator2", "cluster1", lastSynced), "identity1")},
+			expected: false,
+		},
+		{
+			name: "Given a shard of the new operator holding another identity of the cluster, " +
+				"When the shard completed a sync, " +
+				"Then the handoff should not be acknowledged",
+			shards:   []typeV1.Shard{withIdentity(getTestAcknowledgedShard("operator2", "cluster1", testNow), "identity2")},
+			expected: false,
+		},
+		{
+			name: "Given a shard of another operator holding the cluster, " +
+				"When the shard completed a sync, " +
+				"Then the handoff should not be acknowledged",
+			shards:   []typeV1.Shard{withIdentity(getTestAcknowledgedShard("operator3", "cluster1", testNow), "identity1")},
+			expected: false,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := isHandoffAcknowledged(c.shards, migration, params)
+			if actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -1 +1,148 @@
 package manager
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+// Interface to distribute cluster configuration amongst admiral operators
+type LoadDistributor interface {
+	// assigns clusters to operators
+	// clusters present in current stay with the operator they are mapped to, remaining clusters are
+	// distributed amongst the provided operators
+	Distribute(clusters []registry.ClusterConfig, operators []model.Operator, current map[string]string) (model.ShardAssignment, error)
+}
+
+// initializes load distributor for the configured strategy, the strategy is applied within every environment
+// pool when environment pools are configured
+func NewLoadDistributor(params *model.ShardingManagerParams) (LoadDistributor, error) {
+	distributor, err := newStrategyDistributor(params)
+	if err != nil || len(params.EnvironmentPools) == 0 {
+		return distributor, err
+	}
+	return NewEnvironmentDistributor(distributor, params.EnvironmentPools), nil
+}
+
+func newStrategyDistributor(params *model.ShardingManagerParams) (LoadDistributor, error) {
+	switch params.DistributionStrategy {
+	case model.LeastLoadedStrategy, "":
+		return NewLeastLoadedDistributor(), nil
+	case model.LocalityAwareStrategy:
+		return NewLocalityAwareDistributor(), nil
+	case model.SegmentedStrategy:
+		return NewSegmentedDistributor(params.SegmentKey), nil
+	case model.DependencyAwareStrategy:
+		return NewDependencyAwareDistributor(), nil
+	default:
+		return nil, fmt.Errorf("unknown distribution strategy %q", params.DistributionStrategy)
+	}
+}
+
+// assigns every unowned cluster to the operator with the least load relative to its capacity
+// load of a cluster is the number of identities it hosts, so operators with a higher capacity
+// receive proportionally more identities. Clusters which already have an operator are not moved when
+// capacities change, they are only moved by rebalancing once the rebalance threshold is exceeded
+type leastLoadedDistributor struct {
+	// restricts candidates to operators in the same locality as the cluster when any is available
+	localityAware bool
+}
+
+func NewLeastLoadedDistributor() *leastLoadedDistributor {
+	return &leastLoadedDistributor{}
+}
+
+func NewLocalityAwareDistributor() *leastLoadedDistributor {
+	return &leastLoadedDistributor{localityAware: true}
+}
+
+func (d *leastLoadedDistributor) Distribute(clusters []registry.ClusterConfig, operators []model.Operator, current map[string]string) (model.ShardAssignment, error) {
+	var (
+		assignment = make(model.ShardAssignment)
+		load       = make(map[string]int)
+		unowned    []registry.ClusterConfig
+	)
+	for _, operator := range operators {
+		assignment[operator.Identity] = []registry.ClusterConfig{}
+	}
+	for _, cluster := range clusters {
+		owner, ok := current[getClusterKey(cluster)]
+		if !ok {
+			unowned = append(unowned, cluster)
+			continue
+		}
+		assignment[owner] = append(assignment[owner], cluster)
+		load[owner] += getClusterLoad(cluster)
+	}
+	if len(unowned) > 0 && len(operators) == 0 {
+		return nil, fmt.Errorf("no operators available to assign %d clusters", len(unowned))
+	}
+
+	// place heaviest clusters first so that lighter clusters can even out the remaining imbalance
+	sort.Slice(unowned, func(i, j int) bool {
+		if getClusterLoad(unowned[i]) != getClusterLoad(unowned[j]) {
+			return getClusterLoad(unowned[i]) > getClusterLoad(unowned[j])
+		}
+		return getClusterKey(unowned[i]) < getClusterKey(unowned[j])
+	})
+	for _, cluster := range unowned {
+		candidates := operators
+		if d.localityAware {
+			candidates = getLocalOperators(cluster, operators)
+		}
+		target := leastLoadedOperator(load, getClusterLoad(cluster), candidates)
+		assignment[target] = append(assignment[target], cluster)
+		load[target] += getClusterLoad(cluster)
+	}
+	return assignment, nil
+}
+
+// returns identity of the operator which would have the least load relative to its capacity after
+// taking on the additional load, ties are broken by operator identity
+func leastLoadedOperator(load map[string]int, additionalLoad int, operators []model.Operator) string {
+	var (
+		target     string
+		targetLoad float64
+	)
+	for _, operator := range operators {
+		weightedLoad := float64(load[operator.Identity]+additionalLoad) / getOperatorCapacity(operator)
+		if target == "" ||
+			weightedLoad < targetLoad ||
+			(weightedLoad == targetLoad && operator.Identity < target) {
+			target = operator.Identity
+			targetLoad = weightedLoad
+		}
+	}
+	return target
+}
+
+// returns operators running in the cluster's locality, or all operators when none does
+func getLocalOperators(cluster registry.ClusterConfig, operators []model.Operator) []model.Operator {
+	var local []model.Operator
+	for _, operator := range operators {
+		if operator.Locality != "" && operator.Locality == cluster.Locality {
+			local = append(local, operator)
+		}
+	}
+	if len(local) == 0 {
+		return operators
+	}
+	return local
+}
+
+// load contributed by a cluster, clusters without identities still count as a unit of work
+func getClusterLoad(cluster registry.ClusterConfig) int {
+	if len(cluster.IdentityConfig.AssetList) == 0 {
+		return 1
+	}
+	return len(cluster.IdentityConfig.AssetList)
+}
+
+func getOperatorCapacity(operator model.Operator) float64 {
+	if operator.Capacity <= 0 {
+		return model.DefaultOperatorCapacity
+	}
+	return operator.Capacity
+}
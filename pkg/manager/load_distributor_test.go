@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+func getTestCluster(name string, identities ...string) registry.ClusterConfig {
+	cluster := registry.ClusterConfig{
+		Name: name,
+		IdentityConfig: registry.IdentityConfig{
+			ClusterName: name,
+		},
+	}
+	for _, identity := range identities {
+		cluster.IdentityConfig.AssetList = append(cluster.IdentityConfig.AssetList, registry.AssetList{
+			Name:        identity,
+			Environment: "qal",
+		})
+	}
+	return cluster
+}
+
+func TestLeastLoadedDistributor(t *testing.T) {
+	testCases := []struct {
+		name           string
+		clusters       []registry.ClusterConfig
+		operators      []model.Operator
+		current        map[string]string
+		expectedOwners map[string]string
+		expectedError  bool
+	}{
+		{
+			name: "Given operators with equal capacity and clusters with different number of identities, " +
+				"When clusters are distributed, " +
+				"Then identities should be balanced instead of clusters",
+			clusters: []registry.ClusterConfig{
+				getTestCluster("cluster1", "identity1", "identity2", "identity3"),
+				getTestCluster("cluster2", "identity4"),
+				getTestCluster("cluster3", "identity5"),
+				getTestCluster("cluster4", "identity6"),
+			},
+			operators:      []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}},
+			current:        map[string]string{},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator2", "cluster4": "operator2"},
+		},
+		{
+			name: "Given operators with different capacity, " +
+				"When clusters are distributed, " +
+				"Then operator with higher capacity should receive proportionally more load",
+			clusters: []registry.ClusterConfig{
+				getTestCluster("cluster1", "identity1"),
+				getTestCluster("cluster2", "identity2"),
+				getTestCluster("cluster3", "identity3"),
+				getTestCluster("cluster4", "identity4"),
+			},
+			operators:      []model.Operator{{Identity: "operator1", Capacity: 3}, {Identity: "operator2", Capacity: 1}},
+			current:        map[string]string{},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1", "cluster4": "operator2"},
+		},
+		{
+			name: "Given clusters which are already assigned, " +
+				"When clusters are distributed, " +
+				"Then they should stay with their operator and count towards its load",
+			clusters: []registry.ClusterConfig{
+				getTestCluster("cluster1", "identity1", "identity2"),
+				getTestCluster("cluster2", "identity3"),
+			},
+			operators:      []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}},
+			current:        map[string]string{"cluster1": "operator2"},
+			expectedOwners: map[string]string{"cluster1": "operator2", "cluster2": "operator1"},
+		},
+		{
+			name: "Given unassigned clusters and no operators, " +
+				"When clusters are distributed, " +
+				"Then there should be non nil error",
+			clusters:      []registry.ClusterConfig{getTestCluster("cluster1", "identity1")},
+			current:       map[string]string{},
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, err := NewLeastLoadedDistributor().Distribute(c.clusters, c.operators, c.current)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error while distributing clusters")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error while distributing clusters: %v", err)
+			}
+			actualOwners := getOwners(assignment)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+		})
+	}
+}
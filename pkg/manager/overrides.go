@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// result of applying placement overrides to the registry configuration
+type overridePlacement struct {
+	// clusters to distribute, excluded clusters and identities are removed
+	clusters []registry.ClusterConfig
+	// operator each pinned cluster is assigned to
+	pinned map[string]string
+	status model.OverrideStatus
+}
+
+// loads placement overrides, the last valid overrides are kept when overrides cannot be loaded
+func (sm *shardingManager) loadOverrides(ctx context.Context, previous []model.PlacementOverride) []model.PlacementOverride {
+	if sm.overrideHandler == nil {
+		return nil
+	}
+	overrides, err := sm.overrideHandler.List(ctx)
+	if err != nil {
+		logrus.Errorf("failed to load placement overrides, using last valid overrides: %v", err)
+		sm.recordEvent(sm.overrideHandler.Reference(), coreV1.EventTypeWarning, overridesInvalidReason, err.Error())
+		return previous
+	}
+	return overrides
+}
+
+// validates placement overrides and applies the valid ones, overrides are applied in order and an override
+// which conflicts with an earlier one is rejected
+func applyPlacementOverrides(
+	clusters []registry.ClusterConfig,
+	overrides []model.PlacementOverride,
+	health map[string]operatorHealth) overridePlacement {
+	var (
+		placement = overridePlacement{
+			pinned: make(map[string]string),
+			status: model.OverrideStatus{
+				Applied:  []model.PlacementOverride{},
+				Rejected: []model.RejectedOverride{},
+			},
+		}
+		excludedClusters   = make(map[string]bool)
+		excludedIdentities = make(map[string]bool)
+	)
+	for _, override := range overrides {
+		targets, err := validatePlacementOverride(override, clusters, health)
+		if err == nil {
+			err = checkOverrideConflicts(override, targets, placement.pinned, excludedClusters)
+		}
+		if err != nil {
+			placement.status.Rejected = append(placement.status.Rejected, model.RejectedOverride{
+				Override: override,
+				Reason:   err.Error(),
+			})
+			continue
+		}
+		switch {
+		case override.Exclude && override.Identity != "":
+			excludedIdentities[override.Identity] = true
+		case override.Exclude:
+			for _, target := range targets {
+				excludedClusters[target] = true
+			}
+		default:
+			for _, target := range targets {
+				placement.pinned[target] = override.Operator
+			}
+		}
+		placement.status.Applied = append(placement.status.Applied, override)
+	}
+
+	for _, cluster := range clusters {
+		if excludedClusters[getClusterKey(cluster)] {
+			continue
+		}
+		if len(excludedIdentities) > 0 {
+			var assets []registry.AssetList
+			for _, asset := range cluster.IdentityConfig.AssetList {
+				if !excludedIdentities[asset.Name] {
+					assets = append(assets, asset)
+				}
+			}
+			cluster.IdentityConfig.AssetList = assets
+		}
+		placement.clusters = append(placement.clusters, cluster)
+	}
+	return placement
+}
+
+// validates the override and returns keys of the clusters it targets, an override of a split cluster targets
+// every part of it while an identity override only targets the part hosting the identity
+func validatePlacementOverride(override model.PlacementOverride, clusters []registry.ClusterConfig, health map[string]operatorHealth) ([]string, error) {
+	var (
+		targets  []string
+		selector labels.Selector
+	)
+	set := 0
+	for _, field := range []string{override.Cluster, override.Identity, override.ClusterSelector} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one of cluster, identity or clusterSelector must be set")
+	}
+	if override.ClusterSelector != "" {
+		var err error
+		selector, err = labels.Parse(override.ClusterSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster selector: %v", err)
+		}
+	}
+	if override.Exclude == (override.Operator != "") {
+		return nil, fmt.Errorf("exactly one of operator or exclude must be set")
+	}
+	if !override.Exclude {
+		state, ok := health[override.Operator]
+		if !ok {
+			return nil, fmt.Errorf("operator %s is not discovered", override.Operator)
+		}
+		if state == operatorFailed {
+			return nil, fmt.Errorf("operator %s is unhealthy", override.Operator)
+		}
+	}
+	for _, cluster := range clusters {
+		if cluster.Name == override.Cluster ||
+			(selector != nil && selector.Matches(labels.Set(cluster.Metadata.Labels()))) {
+			targets = append(targets, getClusterKey(cluster))
+			continue
+		}
+		for _, asset := range cluster.IdentityConfig.AssetList {
+			if override.Identity != "" && asset.Name == override.Identity {
+				targets = append(targets, getClusterKey(cluster))
+				break
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no cluster in registry matches the override")
+	}
+	return targets, nil
+}
+
+func checkOverrideConflicts(override model.PlacementOverride, targets []string, pinned map[string]string, excludedClusters map[string]bool) error {
+	// excluding an identity only removes it from its clusters, which does not affect their placement
+	if override.Exclude && override.Identity != "" {
+		return nil
+	}
+	for _, target := range targets {
+		if excludedClusters[target] {
+			return fmt.Errorf("cluster %s is excluded by an earlier override", target)
+		}
+		if operator, ok := pinned[target]; ok && (override.Exclude || operator != override.Operator) {
+			return fmt.Errorf("cluster %s is pinned to operator %s by an earlier override", target, operator)
+		}
+	}
+	return nil
+}
+
+// records an event for every override which was not rejected by the previous sync
+func (sm *shardingManager) reportRejectedOverrides(previous []model.RejectedOverride, rejected []model.RejectedOverride) {
+	reported := make(map[model.RejectedOverride]bool)
+	for _, rejectedOverride := range previous {
+		reported[rejectedOverride] = true
+	}
+	for _, rejectedOverride := range rejected {
+		if reported[rejectedOverride] {
+			continue
+		}
+		message := fmt.Sprintf("placement override %+v rejected: %s", rejectedOverride.Override, rejectedOverride.Reason)
+		logrus.Warn(message)
+		if sm.overrideHandler != nil {
+			sm.recordEvent(sm.overrideHandler.Reference(), coreV1.EventTypeWarning, overrideRejectedReason, message)
+		}
+	}
+}
+
+// returns placement overrides applied and rejected by the last sync
+func (sm *shardingManager) GetOverrideStatus() model.OverrideStatus {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return sm.overrideStatus
+}
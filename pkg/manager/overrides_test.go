@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+func TestApplyPlacementOverrides(t *testing.T) {
+	clusters := []registry.ClusterConfig{
+		getTestCluster("cluster1", "identity1", "identity2"),
+		getTestCluster("cluster2", "identity2"),
+		getTestCluster("cluster3", "identity3"),
+	}
+	clusters[0].Metadata = registry.ClusterMetadata{Segment: "payments", Tier: "gold"}
+	clusters[1].Metadata = registry.ClusterMetadata{Segment: "payments", Tier: "silver"}
+	health := map[string]operatorHealth{
+		"operator1": operatorHealthy,
+		"operator2": operatorHealthy,
+		"operator3": operatorFailed,
+	}
+	testCases := []struct {
+		name             string
+		overrides        []model.PlacementOverride
+		expectedClusters map[string][]string
+		expectedPinned   map[string]string
+		expectedRejected int
+	}{
+		{
+			name: "Given a cluster pinned to an operator, " +
+				"When placement overrides are applied, " +
+				"Then the cluster should be pinned to the operator",
+			overrides:        []model.PlacementOverride{{Cluster: "cluster1", Operator: "operator2"}},
+			expectedClusters: map[string][]string{"cluster1": {"identity1", "identity2"}, "cluster2": {"identity2"}, "cluster3": {"identity3"}},
+			expectedPinned:   map[string]string{"cluster1": "operator2"},
+		},
+		{
+			name: "Given an identity pinned to an operator, " +
+				"When placement overrides are applied, " +
+				"Then every cluster hosting the identity should be pinned to the operator",
+			overrides:        []model.PlacementOverride{{Identity: "identity2", Operator: "operator1"}},
+			expectedClusters: map[string][]string{"cluster1": {"identity1", "identity2"}, "cluster2": {"identity2"}, "cluster3": {"identity3"}},
+			expectedPinned:   map[string]string{"cluster1": "operator1", "cluster2": "operator1"},
+		},
+		{
+			name: "Given excluded cluster and identity, " +
+				"When placement overrides are applied, " +
+				"Then they should be removed from the clusters to distribute",
+			overrides:        []model.PlacementOverride{{Cluster: "cluster3", Exclude: true}, {Identity: "identity2", Exclude: true}},
+			expectedClusters: map[string][]string{"cluster1": {"identity1"}, "cluster2": nil},
+			expectedPinned:   map[string]string{},
+		},
+		{
+			name: "Given a cluster selector pinned to an operator, " +
+				"When placement overrides are applied, " +
+				"Then every cluster whose metadata matches the selector should be pinned to the operator",
+			overrides:        []model.PlacementOverride{{ClusterSelector: "segment=payments,tier!=silver", Operator: "operator2"}},
+			expectedClusters: map[string][]string{"cluster1": {"identity1", "identity2"}, "cluster2": {"identity2"}, "cluster3": {"identity3"}},
+			expectedPinned:   map[string]string{"cluster1": "operator2"},
+		},
+		{
+			name: "Given an excluded cluster selector, " +
+				"When placement overrides are applied, " +
+				"Then every cluster whose metadata matches the selector should be removed from the clusters to distribute",
+			overrides:        []model.PlacementOverride{{ClusterSelector: "segment=payments", Exclude: true}},
+			expectedClusters: map[string][]string{"cluster3": {"identity3"}},
+			expectedPinned:   map[string]string{},
+		},
+		{
+			name: "Given invalid and conflicting overrides, " +
+				"When placement overrides are applied, " +
+				"Then they should be rejected",
+			overrides: []model.PlacementOverride{
+				{Cluster: "cluster1", Operator: "operator1"},
+				{Identity: "identity1", Operator: "operator2"},
+				{Cluster: "cluster1", Exclude: true},
+				{Cluster: "cluster2", Identity: "identity2", Operator: "operator1"},
+				{Cluster: "cluster2"},
+				{Cluster: "cluster2", Operator: "unknown"},
+				{Cluster: "cluster2", Operator: "operator3"},
+				{Cluster: "unknown", Exclude: true},
+				{ClusterSelector: "segment in (", Exclude: true},
+				{Cluster: "cluster2", ClusterSelector: "segment=payments", Exclude: true},
+				{ClusterSelector: "segment=unknown", Exclude: true},
+				{Cluster: "cluster2", Operator: "operator1", Exclude: true},
+			},
+			expectedClusters: map[string][]string{"cluster1": {"identity1", "identity2"}, "cluster2": {"identity2"}, "cluster3": {"identity3"}},
+			expectedPinned:   map[string]string{"cluster1": "operator1"},
+			expectedRejected: 11,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			placement := applyPlacementOverrides(clusters, c.overrides, health)
+			actualClusters := make(map[string][]string)
+			for _, cluster := range placement.clusters {
+				var identities []string
+				for _, asset := range cluster.IdentityConfig.AssetList {
+					identities = append(identities, asset.Name)
+				}
+				actualClusters[cluster.Name] = identities
+			}
+			if !cmp.Equal(actualClusters, c.expectedClusters) {
+				t.Errorf(cmp.Diff(actualClusters, c.expectedClusters))
+			}
+			if !cmp.Equal(placement.pinned, c.expectedPinned) {
+				t.Errorf(cmp.Diff(placement.pinned, c.expectedPinned))
+			}
+			if len(placement.status.Rejected) != c.expectedRejected {
+				t.Errorf("expected %d rejected overrides, got %v", c.expectedRejected, placement.status.Rejected)
+			}
+			if len(placement.status.Applied)+len(placement.status.Rejected) != len(c.overrides) {
+				t.Errorf("expected every override to be either applied or rejected")
+			}
+		})
+	}
+}
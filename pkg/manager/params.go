@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/schedule"
+	"github.com/sirupsen/logrus"
+)
+
+// applies settings which can be changed without a restart, settings used to discover operators and
+// configuration or to identify shards only take effect after a restart. Settings are replaced as a whole
+// as handlers read them without holding the lock, a sync in progress holds the lock so that the settings
+// only take effect from the next sync
+func (sm *shardingManager) UpdateParams(params model.ShardingManagerParams) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	for setting, changed := range map[string]bool{
+		"shard-identity":          params.ShardingManagerIdentity != sm.params.ShardingManagerIdentity,
+		"operator-identity-label": params.OperatorIdentityLabel != sm.params.OperatorIdentityLabel,
+		"shard-namespace":         params.ShardNamespace != sm.params.ShardNamespace,
+		"registry-endpoint":       params.RegistryEndpoint != sm.params.RegistryEndpoint,
+		"operator-capacity-key":   params.OperatorCapacityKey != sm.params.OperatorCapacityKey,
+		"operator-locality-label": params.OperatorLocalityLabel != sm.params.OperatorLocalityLabel,
+		"operator-segment-label":  params.OperatorSegmentLabel != sm.params.OperatorSegmentLabel,
+		"operator-pool-label":     params.OperatorPoolLabel != sm.params.OperatorPoolLabel,
+	} {
+		if changed {
+			logrus.Warnf("change of setting %s is ignored until sharding manager is restarted", setting)
+		}
+	}
+	updated := *sm.params
+	updated.DistributionStrategy = params.DistributionStrategy
+	updated.SegmentKey = params.SegmentKey
+	updated.EnvironmentPools = params.EnvironmentPools
+	updated.ShardingGranularity = params.ShardingGranularity
+	updated.OperatorGracePeriod = params.OperatorGracePeriod
+	updated.FailoverRecoveryPolicy = params.FailoverRecoveryPolicy
+	updated.MaxClustersPerShard = params.MaxClustersPerShard
+	updated.MaxIdentitiesPerShard = params.MaxIdentitiesPerShard
+	updated.MaxShardSizeBytes = params.MaxShardSizeBytes
+	updated.OverridesConfigMap = params.OverridesConfigMap
+	updated.RevisionHistoryConfigMap = params.RevisionHistoryConfigMap
+	updated.RevisionHistoryLimit = params.RevisionHistoryLimit
+	updated.DrainBatchSize = params.DrainBatchSize
+	updated.SyncPeriod = params.SyncPeriod
+	updated.HandoffTimeout = params.HandoffTimeout
+	updated.MaxRegistryRemovalPercent = params.MaxRegistryRemovalPercent
+	updated.MaxRegistryRemovals = params.MaxRegistryRemovals
+	updated.RolloutMaxMoves = params.RolloutMaxMoves
+	updated.RolloutInterval = params.RolloutInterval
+	updated.RebalanceWindows = params.RebalanceWindows
+	updated.RebalanceThreshold = params.RebalanceThreshold
+	updated.RebalanceHysteresis = params.RebalanceHysteresis
+	loadDistributor, err := NewLoadDistributor(&updated)
+	if err != nil {
+		return err
+	}
+	windows, err := schedule.ParseWindows(updated.RebalanceWindows)
+	if err != nil {
+		return err
+	}
+	sm.loadDistributor = loadDistributor
+	sm.windows = windows
+	sm.params = &updated
+	for _, handler := range []any{sm.shardHandler, sm.operatorHandler, sm.overrideHandler, sm.revisionHandler} {
+		if updater, ok := handler.(controller.ParamsUpdater); ok {
+			updater.UpdateParams(&updated)
+		}
+	}
+	logrus.Infof("reloaded sharding manager settings")
+	return nil
+}
+
+func (sm *shardingManager) getSyncPeriod() time.Duration {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	return sm.params.SyncPeriod
+}
@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateParams(t *testing.T) {
+	testCases := []struct {
+		name           string
+		params         model.ShardingManagerParams
+		expectedParams model.ShardingManagerParams
+		expectedError  bool
+	}{
+		{
+			name: "Given reloaded settings, " +
+				"When settings are updated, " +
+				"Then safe settings should be applied and other settings ignored",
+			params: model.ShardingManagerParams{
+				ShardNamespace:         "other-namespace",
+				DistributionStrategy:   model.LocalityAwareStrategy,
+				OperatorGracePeriod:    time.Minute,
+				FailoverRecoveryPolicy: model.StayRecoveryPolicy,
+				MaxClustersPerShard:    10,
+				DrainBatchSize:         2,
+				SyncPeriod:             time.Minute,
+			},
+			expectedParams: model.ShardingManagerParams{
+				DistributionStrategy:   model.LocalityAwareStrategy,
+				OperatorGracePeriod:    time.Minute,
+				FailoverRecoveryPolicy: model.StayRecoveryPolicy,
+				MaxClustersPerShard:    10,
+				DrainBatchSize:         2,
+				SyncPeriod:             time.Minute,
+			},
+		},
+		{
+			name: "Given reloaded settings with an unknown strategy, " +
+				"When settings are updated, " +
+				"Then there should be non nil error and settings should be unchanged",
+			params: model.ShardingManagerParams{
+				DistributionStrategy: "unknown",
+				SyncPeriod:           time.Minute,
+			},
+			expectedParams: model.ShardingManagerParams{
+				OperatorGracePeriod:    30 * time.Second,
+				FailoverRecoveryPolicy: model.FailbackRecoveryPolicy,
+			},
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+			err := sm.UpdateParams(c.params)
+			if c.expectedError != (err != nil) {
+				t.Errorf("expected error %v, got %v", c.expectedError, err)
+			}
+			if !cmp.Equal(*sm.params, c.expectedParams) {
+				t.Errorf(cmp.Diff(*sm.params, c.expectedParams))
+			}
+		})
+	}
+}
+
+func TestUpdateParamsHandlers(t *testing.T) {
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	previous := sm.params
+	clusters := []registry.ClusterConfig{getTestCluster("cluster1"), getTestCluster("cluster2")}
+
+	// handlers read settings while they are reloaded
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			sm.shardHandler.Partition(clusters, "operator1")
+		}
+	}()
+	err := sm.UpdateParams(model.ShardingManagerParams{MaxClustersPerShard: 1, SyncPeriod: time.Minute})
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("unexpected error while updating settings: %v", err)
+	}
+	if previous.MaxClustersPerShard != 0 {
+		t.Errorf("expected previous settings to be left untouched, got %d clusters per shard", previous.MaxClustersPerShard)
+	}
+	if partitions := sm.shardHandler.Partition(clusters, "operator1"); len(partitions) != 2 {
+		t.Errorf("expected shard handler to partition with reloaded settings, got %d partitions", len(partitions))
+	}
+}
+
+func TestUpdateParamsDuringSync(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: client.AdmiralV1()}, sm.params)
+	sm.registryClient = &testRegistryClient{clusters: []registry.ClusterConfig{getTestCluster("cluster1"), getTestCluster("cluster2")}}
+	listed, release := make(chan struct{}), make(chan struct{})
+	sm.operatorHandler = &testOperatorHandler{
+		operators: []model.Operator{getTestOperator("operator1", time.Now())},
+		listing: func() {
+			close(listed)
+			<-release
+		},
+	}
+
+	// settings are reloaded while the sync discovers operators
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var syncErr error
+	go func() {
+		defer wg.Done()
+		syncErr = sm.bulkSync(ctx)
+	}()
+	<-listed
+	updated := make(chan error)
+	go func() {
+		updated <- sm.UpdateParams(model.ShardingManagerParams{MaxClustersPerShard: 1, SyncPeriod: time.Minute})
+	}()
+	close(release)
+	wg.Wait()
+	if syncErr != nil {
+		t.Fatalf("unexpected error while syncing: %v", syncErr)
+	}
+	if err := <-updated; err != nil {
+		t.Fatalf("unexpected error while updating settings: %v", err)
+	}
+
+	shards, err := client.AdmiralV1().Shards("shard-namespace").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list shards: %v", err)
+	}
+	if len(shards.Items) != 1 {
+		t.Errorf("expected the sync to push shards with the settings it started with, got %d shards", len(shards.Items))
+	}
+	if sm.params.MaxClustersPerShard != 1 {
+		t.Errorf("expected reloaded settings to apply once the sync completed, got %d clusters per shard", sm.params.MaxClustersPerShard)
+	}
+}
@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+// identifies the part of a cluster handled by a single operator, clusters which are not split are
+// identified by their name
+func getClusterKey(cluster registry.ClusterConfig) string {
+	if cluster.Partition == "" {
+		return cluster.Name
+	}
+	return cluster.Name + "/" + cluster.Partition
+}
+
+// splits clusters into the parts handled by different operators according to the configured partitioning,
+// clusters are returned unchanged when no partitioning is configured
+func partitionClusters(clusters []registry.ClusterConfig, params *model.ShardingManagerParams) []registry.ClusterConfig {
+	if len(params.EnvironmentPools) > 0 {
+		clusters = partitionByEnvironment(clusters, params.EnvironmentPools)
+	}
+	if params.ShardingGranularity == model.IdentityShardingGranularity {
+		clusters = partitionByIdentity(clusters)
+	}
+	return clusters
+}
+
+// splits every cluster into one part for each of its identities, so that every (cluster, identity) pair is
+// distributed on its own. Clusters without identities are not split
+func partitionByIdentity(clusters []registry.ClusterConfig) []registry.ClusterConfig {
+	var partitioned []registry.ClusterConfig
+	for _, cluster := range clusters {
+		if len(cluster.IdentityConfig.AssetList) == 0 {
+			partitioned = append(partitioned, cluster)
+			continue
+		}
+		for _, asset := range cluster.IdentityConfig.AssetList {
+			part := cluster
+			part.Partition = asset.Name
+			part.IdentityConfig.AssetList = []registry.AssetList{asset}
+			partitioned = append(partitioned, part)
+		}
+	}
+	return partitioned
+}
+
+// checks that every (cluster, identity) pair is assigned to at most one operator
+func validateAssignment(assignment model.ShardAssignment) error {
+	var (
+		owners     = make(map[string]string)
+		operators  []string
+		duplicates []string
+	)
+	for operatorIdentity := range assignment {
+		operators = append(operators, operatorIdentity)
+	}
+	sort.Strings(operators)
+	for _, operatorIdentity := range operators {
+		for _, cluster := range assignment[operatorIdentity] {
+			for _, asset := range cluster.IdentityConfig.AssetList {
+				pair := cluster.Name + "/" + asset.Name
+				if owner, ok := owners[pair]; ok && owner != operatorIdentity {
+					duplicates = append(duplicates, fmt.Sprintf("%s is assigned to %s and %s", pair, owner, operatorIdentity))
+					continue
+				}
+				owners[pair] = operatorIdentity
+			}
+		}
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("identities are assigned to more than one operator: %s", strings.Join(duplicates, ", "))
+	}
+	return nil
+}
+
+// splits every cluster into one part for each pool its identities' environments belong to, identities of
+// environments without a pool and clusters without identities form the part of no pool
+func partitionByEnvironment(clusters []registry.ClusterConfig, environmentPools map[string]string) []registry.ClusterConfig {
+	var partitioned []registry.ClusterConfig
+	for _, cluster := range clusters {
+		assets := make(map[string][]registry.AssetList)
+		for _, asset := range cluster.IdentityConfig.AssetList {
+			pool := environmentPools[asset.Environment]
+			assets[pool] = append(assets[pool], asset)
+		}
+		if len(assets) == 0 {
+			partitioned = append(partitioned, cluster)
+			continue
+		}
+		var pools []string
+		for pool := range assets {
+			pools = append(pools, pool)
+		}
+		sort.Strings(pools)
+		for _, pool := range pools {
+			part := cluster
+			part.Partition = pool
+			part.IdentityConfig.AssetList = assets[pool]
+			partitioned = append(partitioned, part)
+		}
+	}
+	return partitioned
+}
+
+// clusters of the provided shards with the identities each shard handles, keyed by operator identity
+func getShardClusters(shards []typeV1.Shard, operatorIdentityLabel string) map[string][]registry.ClusterConfig {
+	clusters := make(map[string][]registry.ClusterConfig)
+	for _, shard := range shards {
+		operatorIdentity := shard.Labels[operatorIdentityLabel]
+		for _, clusterShard := range shard.Spec.Clusters {
+			cluster := registry.ClusterConfig{
+				Name:     clusterShard.Name,
+				Locality: clusterShard.Locality,
+				IdentityConfig: registry.IdentityConfig{
+					ClusterName: clusterShard.Name,
+				},
+			}
+			for _, identity := range clusterShard.Identities {
+				cluster.IdentityConfig.AssetList = append(cluster.IdentityConfig.AssetList, registry.AssetList{
+					Name:        identity.Name,
+					Environment: identity.Environment,
+				})
+			}
+			clusters[operatorIdentity] = append(clusters[operatorIdentity], cluster)
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// cluster hosting identities of the provided environments, keyed by identity
+func getTestEnvironmentCluster(name string, environments map[string]string) registry.ClusterConfig {
+	cluster := getTestCluster(name)
+	for _, identity := range []string{"identity1", "identity2", "identity3"} {
+		if environment, ok := environments[identity]; ok {
+			cluster.IdentityConfig.AssetList = append(cluster.IdentityConfig.AssetList, registry.AssetList{
+				Name:        identity,
+				Environment: environment,
+			})
+		}
+	}
+	return cluster
+}
+
+func TestPartitionClusters(t *testing.T) {
+	clusters := []registry.ClusterConfig{
+		getTestEnvironmentCluster("cluster1", map[string]string{"identity1": "prd", "identity2": "qal", "identity3": "e2e"}),
+		getTestEnvironmentCluster("cluster2", map[string]string{"identity1": "prd"}),
+		getTestEnvironmentCluster("cluster3", map[string]string{"identity1": "dev"}),
+		getTestCluster("cluster4"),
+	}
+	testCases := []struct {
+		name               string
+		params             model.ShardingManagerParams
+		expectedPartitions map[string][]string
+	}{
+		{
+			name: "Given no environment pools, " +
+				"When clusters are partitioned, " +
+				"Then clusters should not be split",
+			expectedPartitions: map[string][]string{
+				"cluster1": {"identity1", "identity2", "identity3"},
+				"cluster2": {"identity1"},
+				"cluster3": {"identity1"},
+				"cluster4": nil,
+			},
+		},
+		{
+			name: "Given environment pools, " +
+				"When clusters are partitioned, " +
+				"Then clusters should be split into one part for every pool of their identities",
+			params: model.ShardingManagerParams{EnvironmentPools: map[string]string{"prd": "prod", "qal": "non-prod", "e2e": "non-prod"}},
+			expectedPartitions: map[string][]string{
+				"cluster1/non-prod": {"identity2", "identity3"},
+				"cluster1/prod":     {"identity1"},
+				"cluster2/prod":     {"identity1"},
+				"cluster3":          {"identity1"},
+				"cluster4":          nil,
+			},
+		},
+		{
+			name: "Given identity sharding granularity and environment pools, " +
+				"When clusters are partitioned, " +
+				"Then clusters should be split into one part for every identity",
+			params: model.ShardingManagerParams{
+				EnvironmentPools:    map[string]string{"prd": "prod", "qal": "non-prod", "e2e": "non-prod"},
+				ShardingGranularity: model.IdentityShardingGranularity,
+			},
+			expectedPartitions: map[string][]string{
+				"cluster1/identity1": {"identity1"},
+				"cluster1/identity2": {"identity2"},
+				"cluster1/identity3": {"identity3"},
+				"cluster2/identity1": {"identity1"},
+				"cluster3/identity1": {"identity1"},
+				"cluster4":           nil,
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actualPartitions := make(map[string][]string)
+			for _, cluster := range partitionClusters(clusters, &c.params) {
+				var identities []string
+				for _, asset := range cluster.IdentityConfig.AssetList {
+					identities = append(identities, asset.Name)
+				}
+				actualPartitions[getClusterKey(cluster)] = identities
+			}
+			if !cmp.Equal(actualPartitions, c.expectedPartitions) {
+				t.Errorf(cmp.Diff(actualPartitions, c.expectedPartitions))
+			}
+		})
+	}
+}
+
+func TestGetOwnersFromShards(t *testing.T) {
+	shards := []typeV1.Shard{
+		{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{testOperatorIdentityLabel: "operator1"}},
+			Spec: typeV1.ShardSpec{Clusters: []typeV1.ClusterShards{
+				{Name: "cluster1", Identities: []typeV1.IdentityItem{{Name: "identity1", Environment: "prd"}}},
+				{Name: "cluster2", Identities: []typeV1.IdentityItem{{Name: "identity1", Environment: "prd"}}},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{testOperatorIdentityLabel: "operator2"}},
+			Spec: typeV1.ShardSpec{Clusters: []typeV1.ClusterShards{
+				{Name: "cluster1", Identities: []typeV1.IdentityItem{{Name: "identity2", Environment: "qal"}}},
+			}},
+		},
+	}
+	params := &model.ShardingManagerParams{
+		OperatorIdentityLabel: testOperatorIdentityLabel,
+		EnvironmentPools:      map[string]string{"prd": "prod", "qal": "non-prod"},
+	}
+	expectedOwners := map[string]string{"cluster1/prod": "operator1", "cluster2/prod": "operator1", "cluster1/non-prod": "operator2"}
+	actualOwners := getOwnersFromShards(shards, params)
+	if !cmp.Equal(actualOwners, expectedOwners) {
+		t.Errorf(cmp.Diff(actualOwners, expectedOwners))
+	}
+}
+
+func TestValidateAssignment(t *testing.T) {
+	testCases := []struct {
+		name          string
+		assignment    model.ShardAssignment
+		expectedError string
+	}{
+		{
+			name: "Given identities of a cluster assigned to different operators, " +
+				"When assignment is validated, " +
+				"Then there should be no error",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1")},
+				"operator2": {getTestCluster("cluster1", "identity2")},
+			},
+		},
+		{
+			name: "Given an identity of a cluster assigned to two operators, " +
+				"When assignment is validated, " +
+				"Then there should be an error naming the identity",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1", "identity1")},
+				"operator2": {getTestCluster("cluster1", "identity1", "identity2")},
+			},
+			expectedError: "identities are assigned to more than one operator: cluster1/identity1 is assigned to operator1 and operator2",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var actualError string
+			err := validateAssignment(c.assignment)
+			if err != nil {
+				actualError = err.Error()
+			}
+			if actualError != c.expectedError {
+				t.Errorf(cmp.Diff(actualError, c.expectedError))
+			}
+		})
+	}
+}
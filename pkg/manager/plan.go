@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"math"
+	"sort"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+// distributes clusters amongst operators from scratch using the configured strategy without pushing any shard
+func PlanDistribution(params *model.ShardingManagerParams, clusters []registry.ClusterConfig, operators []model.Operator) (model.DistributionPlan, error) {
+	loadDistributor, err := NewLoadDistributor(params)
+	if err != nil {
+		return model.DistributionPlan{}, err
+	}
+	assignment, err := loadDistributor.Distribute(partitionClusters(clusters, params), operators, map[string]string{})
+	if err != nil {
+		return model.DistributionPlan{}, err
+	}
+	strategy := params.DistributionStrategy
+	if strategy == "" {
+		strategy = model.LeastLoadedStrategy
+	}
+	return buildDistributionPlan(strategy, assignment, operators), nil
+}
+
+func buildDistributionPlan(strategy string, assignment model.ShardAssignment, operators []model.Operator) model.DistributionPlan {
+	plan := model.DistributionPlan{
+		Strategy:  strategy,
+		Operators: []model.OperatorLoad{},
+	}
+	for _, operator := range operators {
+		operatorLoad := model.OperatorLoad{
+			Operator: operator.Identity,
+			Locality: operator.Locality,
+			Segment:  operator.Segment,
+			Pool:     operator.Pool,
+			Capacity: getOperatorCapacity(operator),
+			Clusters: []string{},
+		}
+		for _, cluster := range assignment[operator.Identity] {
+			operatorLoad.Clusters = append(operatorLoad.Clusters, getClusterKey(cluster))
+			operatorLoad.Identities += len(cluster.IdentityConfig.AssetList)
+			operatorLoad.Load += getClusterLoad(cluster)
+			if operator.Locality != "" && cluster.Locality != operator.Locality {
+				operatorLoad.CrossLocality++
+			}
+		}
+		sort.Strings(operatorLoad.Clusters)
+		operatorLoad.WeightedLoad = float64(operatorLoad.Load) / operatorLoad.Capacity
+		plan.Operators = append(plan.Operators, operatorLoad)
+	}
+	sort.Slice(plan.Operators, func(i, j int) bool {
+		return plan.Operators[i].Operator < plan.Operators[j].Operator
+	})
+	plan.Statistics = getDistributionStatistics(plan.Operators)
+	plan.Statistics.CrossOperatorDependencies = getCrossOperatorDependencies(assignment)
+	return plan
+}
+
+func getDistributionStatistics(operatorLoads []model.OperatorLoad) model.DistributionStatistics {
+	var (
+		statistics model.DistributionStatistics
+		sum        float64
+	)
+	if len(operatorLoads) == 0 {
+		return statistics
+	}
+	statistics.MinLoad = math.MaxFloat64
+	for _, operatorLoad := range operatorLoads {
+		sum += operatorLoad.WeightedLoad
+		statistics.MaxLoad = math.Max(statistics.MaxLoad, operatorLoad.WeightedLoad)
+		statistics.MinLoad = math.Min(statistics.MinLoad, operatorLoad.WeightedLoad)
+		statistics.CrossLocality += operatorLoad.CrossLocality
+	}
+	mean := sum / float64(len(operatorLoads))
+	var variance float64
+	for _, operatorLoad := range operatorLoads {
+		variance += math.Pow(operatorLoad.WeightedLoad-mean, 2)
+	}
+	statistics.StdDevLoad = math.Sqrt(variance / float64(len(operatorLoads)))
+	return statistics
+}
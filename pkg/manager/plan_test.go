@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+func TestPlanDistribution(t *testing.T) {
+	cluster1 := getTestCluster("cluster1", "identity1", "identity2")
+	cluster1.Locality = "us-west-2"
+	cluster2 := getTestCluster("cluster2", "identity3")
+	cluster2.Locality = "us-east-2"
+	cluster3 := getTestCluster("cluster3", "identity4")
+	cluster3.Locality = "us-east-2"
+	clusters := []registry.ClusterConfig{cluster1, cluster2, cluster3}
+	operators := []model.Operator{
+		{Identity: "operator1", Locality: "us-east-2"},
+		{Identity: "operator2", Locality: "us-west-2"},
+	}
+
+	testCases := []struct {
+		name               string
+		strategy           string
+		expectedClusters   map[string][]string
+		expectedStatistics model.DistributionStatistics
+		expectedError      bool
+	}{
+		{
+			name: "Given least loaded strategy, " +
+				"When distribution is planned, " +
+				"Then load should be balanced regardless of locality",
+			strategy:           model.LeastLoadedStrategy,
+			expectedClusters:   map[string][]string{"operator1": {"cluster1"}, "operator2": {"cluster2", "cluster3"}},
+			expectedStatistics: model.DistributionStatistics{MaxLoad: 2, MinLoad: 2, StdDevLoad: 0, CrossLocality: 3},
+		},
+		{
+			name: "Given locality aware strategy and operators in every locality, " +
+				"When distribution is planned, " +
+				"Then no cluster should be assigned to an operator in another locality",
+			strategy:           model.LocalityAwareStrategy,
+			expectedClusters:   map[string][]string{"operator1": {"cluster2", "cluster3"}, "operator2": {"cluster1"}},
+			expectedStatistics: model.DistributionStatistics{MaxLoad: 2, MinLoad: 2, StdDevLoad: 0, CrossLocality: 0},
+		},
+		{
+			name: "Given an unknown strategy, " +
+				"When distribution is planned, " +
+				"Then there should be non nil error",
+			strategy:      "unknown",
+			expectedError: true,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			plan, err := PlanDistribution(&model.ShardingManagerParams{DistributionStrategy: c.strategy}, clusters, operators)
+			if c.expectedError {
+				if err == nil {
+					t.Errorf("expected error while planning distribution")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error while planning distribution: %v", err)
+			}
+			actualClusters := make(map[string][]string)
+			for _, operatorLoad := range plan.Operators {
+				actualClusters[operatorLoad.Operator] = operatorLoad.Clusters
+			}
+			if !cmp.Equal(actualClusters, c.expectedClusters) {
+				t.Errorf(cmp.Diff(actualClusters, c.expectedClusters))
+			}
+			if !cmp.Equal(plan.Statistics, c.expectedStatistics) {
+				t.Errorf(cmp.Diff(plan.Statistics, c.expectedStatistics))
+			}
+		})
+	}
+}
+
+func TestGetDistributionStatistics(t *testing.T) {
+	statistics := getDistributionStatistics([]model.OperatorLoad{
+		{Operator: "operator1", WeightedLoad: 4, CrossLocality: 1},
+		{Operator: "operator2", WeightedLoad: 2, CrossLocality: 2},
+	})
+	expectedStatistics := model.DistributionStatistics{MaxLoad: 4, MinLoad: 2, StdDevLoad: 1, CrossLocality: 3}
+	if !cmp.Equal(statistics, expectedStatistics) {
+		t.Errorf(cmp.Diff(statistics, expectedStatistics))
+	}
+}
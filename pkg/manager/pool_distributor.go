@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+var unassignedClusters = monitoring.NewGauge(
+	"unassigned_clusters",
+	"number of clusters left unassigned as no operator of their pool is available",
+	monitoring.WithMeter(shardingManagerMeter))
+
+// partitions clusters into pools and distributes each partition amongst the operators of the same pool only,
+// so that an incident in one pool's operators cannot move clusters of that pool to another pool. Clusters
+// without a pool are handled by operators without a pool
+type poolDistributor struct {
+	// kind of pool used in logs and errors
+	kind         string
+	clusterPool  func(cluster registry.ClusterConfig) string
+	operatorPool func(operator model.Operator) string
+	distributor  LoadDistributor
+}
+
+// distributes parts of clusters split by environment amongst the operators of the environment's pool using
+// the provided distributor within every pool
+func NewEnvironmentDistributor(distributor LoadDistributor, environmentPools map[string]string) *poolDistributor {
+	return &poolDistributor{
+		kind: "environment pool",
+		clusterPool: func(cluster registry.ClusterConfig) string {
+			// identities of a part split by environment all belong to the same pool
+			if len(cluster.IdentityConfig.AssetList) == 0 {
+				return ""
+			}
+			return environmentPools[cluster.IdentityConfig.AssetList[0].Environment]
+		},
+		operatorPool: func(operator model.Operator) string {
+			return operator.Pool
+		},
+		distributor: distributor,
+	}
+}
+
+func (d *poolDistributor) Distribute(clusters []registry.ClusterConfig, operators []model.Operator, current map[string]string) (model.ShardAssignment, error) {
+	var (
+		assignment    = make(model.ShardAssignment)
+		operatorPools = make(map[string][]model.Operator)
+		partitions    = make(map[string][]registry.ClusterConfig)
+		pools         = make(map[string]string)
+	)
+	for _, operator := range operators {
+		pool := d.operatorPool(operator)
+		operatorPools[pool] = append(operatorPools[pool], operator)
+		pools[operator.Identity] = pool
+		assignment[operator.Identity] = []registry.ClusterConfig{}
+	}
+	for _, cluster := range clusters {
+		pool := d.clusterPool(cluster)
+		partitions[pool] = append(partitions[pool], cluster)
+	}
+	var names []string
+	for pool := range partitions {
+		names = append(names, pool)
+	}
+	sort.Strings(names)
+
+	for _, pool := range names {
+		// clusters of a pool without available operators stay with their current operator rather than being
+		// moved to another pool, clusters without a current operator are left unassigned so that other pools
+		// are still distributed
+		if len(operatorPools[pool]) == 0 {
+			var unassigned int
+			for _, cluster := range partitions[pool] {
+				owner, ok := current[getClusterKey(cluster)]
+				if !ok {
+					unassigned++
+					continue
+				}
+				assignment[owner] = append(assignment[owner], cluster)
+			}
+			logrus.Warnf("no operator available for %s %q, %d clusters are left unassigned", d.kind, pool, unassigned)
+			continue
+		}
+		// clusters stay with their current operator unless it is known to belong to another pool, operators
+		// which are not available keep their clusters as their pool is not known
+		partitionCurrent := make(map[string]string)
+		for _, cluster := range partitions[pool] {
+			key := getClusterKey(cluster)
+			owner, ok := current[key]
+			if !ok {
+				continue
+			}
+			if ownerPool, available := pools[owner]; available && ownerPool != pool {
+				logrus.Infof("moving cluster %s of %s %q away from operator %s of %s %q", key, d.kind, pool, owner, d.kind, ownerPool)
+				continue
+			}
+			partitionCurrent[key] = owner
+		}
+		partitionAssignment, err := d.distributor.Distribute(partitions[pool], operatorPools[pool], partitionCurrent)
+		if err != nil {
+			return nil, err
+		}
+		for operator, assigned := range partitionAssignment {
+			assignment[operator] = append(assignment[operator], assigned...)
+		}
+	}
+	return assignment, nil
+}
+
+// keys of clusters which are not assigned to any operator
+func getUnassignedClusters(clusters []registry.ClusterConfig, assignment model.ShardAssignment) []string {
+	assigned := make(map[string]bool)
+	for _, operatorClusters := range assignment {
+		for _, cluster := range operatorClusters {
+			assigned[getClusterKey(cluster)] = true
+		}
+	}
+	var unassigned []string
+	for _, cluster := range clusters {
+		if key := getClusterKey(cluster); !assigned[key] {
+			unassigned = append(unassigned, key)
+		}
+	}
+	sort.Strings(unassigned)
+	return unassigned
+}
+
+// reports clusters left unassigned by the last sync, an event is only recorded for clusters which were
+// assigned before
+func (sm *shardingManager) reportUnassignedClusters(previous []string, unassigned []string) {
+	unassignedClusters.Set(int64(len(unassigned)))
+	reported := make(map[string]bool)
+	for _, cluster := range previous {
+		reported[cluster] = true
+	}
+	var added []string
+	for _, cluster := range unassigned {
+		if !reported[cluster] {
+			added = append(added, cluster)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	message := fmt.Sprintf("no operator of their pool is available, clusters %s are left unassigned", strings.Join(added, ", "))
+	logrus.Warn(message)
+	sm.recordEvent(sm.reference, coreV1.EventTypeWarning, clustersUnassignedReason, message)
+}
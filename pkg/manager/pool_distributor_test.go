@@ -0,0 +1,144 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEnvironmentDistributor(t *testing.T) {
+	clusters := partitionByEnvironment([]registry.ClusterConfig{
+		getTestEnvironmentCluster("cluster1", map[string]string{"identity1": "prd", "identity2": "qal"}),
+		getTestEnvironmentCluster("cluster2", map[string]string{"identity1": "prd", "identity3": "dev"}),
+	}, map[string]string{"prd": "prod", "qal": "non-prod"})
+	testCases := []struct {
+		name           string
+		operators      []model.Operator
+		current        map[string]string
+		expectedOwners map[string]string
+	}{
+		{
+			name: "Given an operator pool for every environment, " +
+				"When clusters split by environment are distributed, " +
+				"Then every part should only be assigned to operators of its pool",
+			operators: []model.Operator{
+				{Identity: "operator1", Pool: "prod"},
+				{Identity: "operator2", Pool: "prod"},
+				{Identity: "operator3", Pool: "non-prod"},
+				{Identity: "operator4"},
+			},
+			current: map[string]string{},
+			expectedOwners: map[string]string{
+				"cluster1/prod":     "operator1",
+				"cluster2/prod":     "operator2",
+				"cluster1/non-prod": "operator3",
+				"cluster2":          "operator4",
+			},
+		},
+		{
+			name: "Given a failed non prod pool, " +
+				"When clusters split by environment are distributed, " +
+				"Then non prod parts should stay with their current operator rather than moved to production operators",
+			operators: []model.Operator{
+				{Identity: "operator1", Pool: "prod"},
+				{Identity: "operator2", Pool: "prod"},
+				{Identity: "operator4"},
+			},
+			current: map[string]string{"cluster1/prod": "operator1", "cluster2/prod": "operator2", "cluster1/non-prod": "operator3"},
+			expectedOwners: map[string]string{
+				"cluster1/prod":     "operator1",
+				"cluster2/prod":     "operator2",
+				"cluster1/non-prod": "operator3",
+				"cluster2":          "operator4",
+			},
+		},
+		{
+			name: "Given a failed non prod pool and a non prod part without a current operator, " +
+				"When clusters split by environment are distributed, " +
+				"Then the part should be left unassigned while other pools are still distributed",
+			operators: []model.Operator{
+				{Identity: "operator1", Pool: "prod"},
+				{Identity: "operator2", Pool: "prod"},
+				{Identity: "operator4"},
+			},
+			current: map[string]string{"cluster1/prod": "operator1"},
+			expectedOwners: map[string]string{
+				"cluster1/prod": "operator1",
+				"cluster2/prod": "operator2",
+				"cluster2":      "operator4",
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, err := NewEnvironmentDistributor(NewLeastLoadedDistributor(), map[string]string{"prd": "prod", "qal": "non-prod"}).Distribute(clusters, c.operators, c.current)
+			if err != nil {
+				t.Fatalf("unexpected error while distributing clusters: %v", err)
+			}
+			actualOwners := getOwners(assignment)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+		})
+	}
+}
+
+func TestBulkSyncEnvironmentPoolWithoutOperators(t *testing.T) {
+	ctx := context.Background()
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.params.EnvironmentPools = map[string]string{"prd": "prod", "qal": "non-prod"}
+	sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+	loadDistributor, err := NewLoadDistributor(sm.params)
+	if err != nil {
+		t.Fatalf("failed to initialize load distributor: %v", err)
+	}
+	sm.loadDistributor = loadDistributor
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	sm.registryClient = &testRegistryClient{
+		clusters: []registry.ClusterConfig{
+			getTestEnvironmentCluster("cluster1", map[string]string{"identity1": "prd", "identity2": "qal"}),
+		},
+		resourceVersion: "1",
+	}
+	operator := getTestOperator("operator1", time.Now())
+	operator.Pool = "prod"
+	sm.operatorHandler = &testOperatorHandler{operators: []model.Operator{operator}}
+	previousUnassigned := unassignedClusters
+	unassignedClusters = &testMetric{name: "unassigned_clusters"}
+	t.Cleanup(func() {
+		unassignedClusters = previousUnassigned
+	})
+
+	// the non prod pool has no operator, its new part must not prevent the prod pool from being distributed
+	err = sm.bulkSync(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while syncing: %v", err)
+	}
+	expectedOwners := map[string]string{"cluster1/prod": "operator1"}
+	if !cmp.Equal(sm.owners, expectedOwners) {
+		t.Errorf(cmp.Diff(sm.owners, expectedOwners))
+	}
+	if !cmp.Equal(sm.unassigned, []string{"cluster1/non-prod"}) {
+		t.Errorf(cmp.Diff(sm.unassigned, []string{"cluster1/non-prod"}))
+	}
+	var unassignedEvents int
+	for _, reason := range getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder)) {
+		if reason == clustersUnassignedReason {
+			unassignedEvents++
+		}
+	}
+	if unassignedEvents != 1 {
+		t.Errorf("expected one %s event, got %d", clustersUnassignedReason, unassignedEvents)
+	}
+}
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"sort"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+)
+
+// difference between the highest and lowest weighted load of the operators relative to their average
+// weighted load, operators without load are balanced
+func getImbalance(load map[string]int, operators []model.Operator) float64 {
+	if len(operators) < 2 {
+		return 0
+	}
+	var (
+		totalLoad     int
+		totalCapacity float64
+		maxLoad       float64
+		minLoad       float64
+	)
+	for i, operator := range operators {
+		totalLoad += load[operator.Identity]
+		totalCapacity += getOperatorCapacity(operator)
+		weightedLoad := float64(load[operator.Identity]) / getOperatorCapacity(operator)
+		if i == 0 || weightedLoad > maxLoad {
+			maxLoad = weightedLoad
+		}
+		if i == 0 || weightedLoad < minLoad {
+			minLoad = weightedLoad
+		}
+	}
+	if totalLoad == 0 {
+		return 0
+	}
+	return (maxLoad - minLoad) / (float64(totalLoad) / totalCapacity)
+}
+
+// operators whose clusters can be moved amongst each other, clusters are never moved across segments or
+// environment pools
+func getRebalanceGroup(operator model.Operator, params *model.ShardingManagerParams) string {
+	var group string
+	if params.DistributionStrategy == model.SegmentedStrategy {
+		group = operator.Segment
+	}
+	if len(params.EnvironmentPools) > 0 {
+		group += "/" + operator.Pool
+	}
+	return group
+}
+
+// keeps the assignment as long as the imbalance between operators stays within the rebalance threshold.
+// Once it is exceeded, clusters are moved one at a time from the most loaded operator, picking the move
+// which reduces the imbalance most, until the imbalance is back below the threshold minus the hysteresis
+// so that load which wiggles around the threshold does not move clusters back and forth. Pinned clusters
+// are never moved. Returns the rebalanced assignment along with the moved clusters
+func rebalanceAssignment(
+	assignment model.ShardAssignment,
+	operators []model.Operator,
+	pinned map[string]string,
+	params *model.ShardingManagerParams) (model.ShardAssignment, []string) {
+	if params.RebalanceThreshold <= 0 {
+		return assignment, nil
+	}
+	var (
+		rebalanced = make(model.ShardAssignment)
+		load       = make(map[string]int)
+		groups     = make(map[string][]model.Operator)
+		names      []string
+		moved      []string
+		movedSet   = make(map[string]bool)
+	)
+	for operatorIdentity, clusters := range assignment {
+		rebalanced[operatorIdentity] = append([]registry.ClusterConfig{}, clusters...)
+		for _, cluster := range clusters {
+			load[operatorIdentity] += getClusterLoad(cluster)
+		}
+	}
+	for _, operator := range operators {
+		group := getRebalanceGroup(operator, params)
+		if _, ok := groups[group]; !ok {
+			names = append(names, group)
+		}
+		groups[group] = append(groups[group], operator)
+	}
+	sort.Strings(names)
+
+	bound := params.RebalanceThreshold - params.RebalanceHysteresis
+	for _, group := range names {
+		members := groups[group]
+		imbalance := getImbalance(load, members)
+		if imbalance <= params.RebalanceThreshold {
+			continue
+		}
+		logrus.Infof("imbalance %.2f between operators exceeds rebalance threshold %.2f, moving clusters", imbalance, params.RebalanceThreshold)
+		for imbalance > bound {
+			source := mostLoadedOperator(load, members)
+			var (
+				target string
+				best   = imbalance
+				index  = -1
+			)
+			for i, cluster := range rebalanced[source.Identity] {
+				key := getClusterKey(cluster)
+				if _, ok := pinned[key]; ok || movedSet[key] {
+					continue
+				}
+				clusterLoad := getClusterLoad(cluster)
+				for _, operator := range getRebalanceTargets(cluster, source, members, params) {
+					load[source.Identity] -= clusterLoad
+					load[operator.Identity] += clusterLoad
+					result := getImbalance(load, members)
+					load[source.Identity] += clusterLoad
+					load[operator.Identity] -= clusterLoad
+					if result < best {
+						target, best, index = operator.Identity, result, i
+					}
+				}
+			}
+			if index < 0 {
+				logrus.Warnf("no cluster move reduces imbalance %.2f between operators any further", imbalance)
+				break
+			}
+			cluster := rebalanced[source.Identity][index]
+			rebalanced[source.Identity] = append(rebalanced[source.Identity][:index], rebalanced[source.Identity][index+1:]...)
+			rebalanced[target] = append(rebalanced[target], cluster)
+			load[source.Identity] -= getClusterLoad(cluster)
+			load[target] += getClusterLoad(cluster)
+			movedSet[getClusterKey(cluster)] = true
+			moved = append(moved, getClusterKey(cluster))
+			logrus.Infof("moving cluster %s from operator %s to operator %s to rebalance load", getClusterKey(cluster), source.Identity, target)
+			imbalance = best
+		}
+	}
+	sort.Strings(moved)
+	return rebalanced, moved
+}
+
+// operator with the highest load relative to its capacity, ties are broken by operator identity
+func mostLoadedOperator(load map[string]int, operators []model.Operator) model.Operator {
+	var (
+		source     model.Operator
+		sourceLoad float64
+	)
+	for i, operator := range operators {
+		weightedLoad := float64(load[operator.Identity]) / getOperatorCapacity(operator)
+		if i == 0 || weightedLoad > sourceLoad || (weightedLoad == sourceLoad && operator.Identity < source.Identity) {
+			source, sourceLoad = operator, weightedLoad
+		}
+	}
+	return source
+}
+
+// operators of the group a cluster can be moved to, with the locality aware strategy clusters handled in
+// their locality are only moved within it
+func getRebalanceTargets(cluster registry.ClusterConfig, source model.Operator, operators []model.Operator, params *model.ShardingManagerParams) []model.Operator {
+	var targets, local []model.Operator
+	for _, operator := range operators {
+		if operator.Identity == source.Identity {
+			continue
+		}
+		targets = append(targets, operator)
+		if operator.Locality != "" && operator.Locality == cluster.Locality {
+			local = append(local, operator)
+		}
+	}
+	if params.DistributionStrategy != model.LocalityAwareStrategy {
+		return targets
+	}
+	if len(local) > 0 || (source.Locality != "" && source.Locality == cluster.Locality) {
+		return local
+	}
+	return targets
+}
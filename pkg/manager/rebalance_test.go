@@ -0,0 +1,176 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+)
+
+func TestRebalanceAssignment(t *testing.T) {
+	imbalanced := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster1"), getTestCluster("cluster2"), getTestCluster("cluster3"), getTestCluster("cluster4"), getTestCluster("cluster5")},
+		"operator2": {getTestCluster("cluster6")},
+	}
+	operators := []model.Operator{{Identity: "operator1"}, {Identity: "operator2"}}
+	testCases := []struct {
+		name           string
+		assignment     model.ShardAssignment
+		operators      []model.Operator
+		pinned         map[string]string
+		params         model.ShardingManagerParams
+		expectedOwners map[string][]string
+		expectedMoved  []string
+	}{
+		{
+			name: "Given no rebalance threshold, " +
+				"When an imbalanced assignment is rebalanced, " +
+				"Then the assignment should be kept",
+			assignment: imbalanced,
+			operators:  operators,
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster1", "cluster2", "cluster3", "cluster4", "cluster5"},
+				"operator2": {"cluster6"},
+			},
+		},
+		{
+			name: "Given an imbalance within the rebalance threshold, " +
+				"When the assignment is rebalanced, " +
+				"Then the assignment should be kept",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1"), getTestCluster("cluster2"), getTestCluster("cluster3")},
+				"operator2": {getTestCluster("cluster4"), getTestCluster("cluster5")},
+			},
+			operators: operators,
+			params:    model.ShardingManagerParams{RebalanceThreshold: 0.5},
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster1", "cluster2", "cluster3"},
+				"operator2": {"cluster4", "cluster5"},
+			},
+		},
+		{
+			name: "Given an imbalance beyond the rebalance threshold, " +
+				"When the assignment is rebalanced, " +
+				"Then only the clusters needed to get back within the threshold should be moved",
+			assignment: imbalanced,
+			operators:  operators,
+			params:     model.ShardingManagerParams{RebalanceThreshold: 1},
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster2", "cluster3", "cluster4", "cluster5"},
+				"operator2": {"cluster6", "cluster1"},
+			},
+			expectedMoved: []string{"cluster1"},
+		},
+		{
+			name: "Given a rebalance hysteresis, " +
+				"When the assignment is rebalanced, " +
+				"Then clusters should be moved until the imbalance is below the threshold minus the hysteresis",
+			assignment: imbalanced,
+			operators:  operators,
+			params:     model.ShardingManagerParams{RebalanceThreshold: 1, RebalanceHysteresis: 0.5},
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster3", "cluster4", "cluster5"},
+				"operator2": {"cluster6", "cluster1", "cluster2"},
+			},
+			expectedMoved: []string{"cluster1", "cluster2"},
+		},
+		{
+			name: "Given a pinned cluster, " +
+				"When the assignment is rebalanced, " +
+				"Then the pinned cluster should not be moved",
+			assignment: imbalanced,
+			operators:  operators,
+			pinned:     map[string]string{"cluster1": "operator1"},
+			params:     model.ShardingManagerParams{RebalanceThreshold: 1},
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster1", "cluster3", "cluster4", "cluster5"},
+				"operator2": {"cluster6", "cluster2"},
+			},
+			expectedMoved: []string{"cluster2"},
+		},
+		{
+			name: "Given an operator whose declared capacity was raised, " +
+				"When a balanced assignment is rebalanced, " +
+				"Then clusters should be moved to it according to its capacity",
+			assignment: model.ShardAssignment{
+				"operator1": {getTestCluster("cluster1"), getTestCluster("cluster2"), getTestCluster("cluster3")},
+				"operator2": {getTestCluster("cluster4"), getTestCluster("cluster5"), getTestCluster("cluster6")},
+			},
+			operators: []model.Operator{{Identity: "operator1", Capacity: 2}, {Identity: "operator2"}},
+			params:    model.ShardingManagerParams{RebalanceThreshold: 0.5},
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster1", "cluster2", "cluster3", "cluster4"},
+				"operator2": {"cluster5", "cluster6"},
+			},
+			expectedMoved: []string{"cluster4"},
+		},
+		{
+			name: "Given operators of different environment pools, " +
+				"When the assignment is rebalanced, " +
+				"Then clusters should not be moved across pools",
+			assignment: imbalanced,
+			operators:  []model.Operator{{Identity: "operator1", Pool: "pool1"}, {Identity: "operator2", Pool: "pool2"}},
+			params:     model.ShardingManagerParams{RebalanceThreshold: 1, EnvironmentPools: map[string]string{"prod": "pool1"}},
+			expectedOwners: map[string][]string{
+				"operator1": {"cluster1", "cluster2", "cluster3", "cluster4", "cluster5"},
+				"operator2": {"cluster6"},
+			},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			rebalanced, moved := rebalanceAssignment(c.assignment, c.operators, c.pinned, &c.params)
+			actualOwners := make(map[string][]string)
+			for operatorIdentity, clusters := range rebalanced {
+				for _, cluster := range clusters {
+					actualOwners[operatorIdentity] = append(actualOwners[operatorIdentity], cluster.Name)
+				}
+			}
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+			if !cmp.Equal(moved, c.expectedMoved) {
+				t.Errorf(cmp.Diff(moved, c.expectedMoved))
+			}
+		})
+	}
+}
+
+func TestGetImbalance(t *testing.T) {
+	operators := []model.Operator{{Identity: "operator1", Capacity: 2}, {Identity: "operator2"}}
+	testCases := []struct {
+		name     string
+		load     map[string]int
+		expected float64
+	}{
+		{
+			name: "Given operators loaded according to their capacity, " +
+				"When the imbalance is computed, " +
+				"Then there should be no imbalance",
+			load:     map[string]int{"operator1": 4, "operator2": 2},
+			expected: 0,
+		},
+		{
+			name: "Given operators loaded regardless of their capacity, " +
+				"When the imbalance is computed, " +
+				"Then the difference of their weighted load relative to the average should be returned",
+			load:     map[string]int{"operator1": 3, "operator2": 3},
+			expected: 0.75,
+		},
+		{
+			name: "Given operators without load, " +
+				"When the imbalance is computed, " +
+				"Then there should be no imbalance",
+			load:     map[string]int{},
+			expected: 0,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := getImbalance(c.load, operators)
+			if actual != c.expected {
+				t.Errorf("expected imbalance %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
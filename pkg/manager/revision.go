@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// loads the revision history, the last loaded history is kept when it cannot be read
+func (sm *shardingManager) loadRevisions(ctx context.Context) {
+	if sm.revisionHandler == nil {
+		return
+	}
+	history, err := sm.revisionHandler.Get(ctx)
+	if err != nil {
+		logrus.Errorf("failed to load revision history, using last loaded history: %v", err)
+		return
+	}
+	sm.updateRevisions(history)
+}
+
+// replaces the revision history, rollbacks and resumes done through the command line are reported here
+func (sm *shardingManager) updateRevisions(history model.RevisionHistory) {
+	previous := sm.revisions.FrozenRevision
+	sm.revisions = history
+	switch {
+	case history.FrozenRevision != 0 && history.FrozenRevision != previous:
+		message := fmt.Sprintf("rolled back assignment to revision %d, rebalancing is frozen until it is resumed", history.FrozenRevision)
+		logrus.Warn(message)
+		sm.recordEvent(sm.revisionHandler.Reference(), coreV1.EventTypeNormal, rolledBackReason, message)
+	case history.FrozenRevision == 0 && previous != 0:
+		message := fmt.Sprintf("resumed rebalancing frozen at revision %d", previous)
+		logrus.Info(message)
+		sm.recordEvent(sm.revisionHandler.Reference(), coreV1.EventTypeNormal, rebalancingResumedReason, message)
+	}
+}
+
+// revision the assignment is frozen at, nil when rebalancing is not frozen or the revision is no longer
+// part of the history
+func (sm *shardingManager) getFrozenRevision() *model.AssignmentRevision {
+	if sm.revisions.FrozenRevision == 0 {
+		return nil
+	}
+	for i, revision := range sm.revisions.Revisions {
+		if revision.Revision == sm.revisions.FrozenRevision {
+			return &sm.revisions.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// keeps clusters of the frozen revision with the operator handling them in that revision as long as the
+// operator can handle them, pinned clusters stay where overrides put them. Returns the clusters moved back
+// to the operator of the revision
+func applyRevision(
+	revision *model.AssignmentRevision,
+	clusters []registry.ClusterConfig,
+	current map[string]string,
+	pinned map[string]string,
+	available []model.Operator,
+	owners map[string]string) []string {
+	if revision == nil {
+		return nil
+	}
+	var (
+		revisionOwners = make(map[string]string)
+		targets        = make(map[string]bool)
+		moved          []string
+	)
+	for operatorIdentity, keys := range revision.Operators {
+		for _, key := range keys {
+			revisionOwners[key] = operatorIdentity
+		}
+	}
+	for _, operator := range available {
+		targets[operator.Identity] = true
+	}
+	for _, cluster := range clusters {
+		key := getClusterKey(cluster)
+		owner, ok := revisionOwners[key]
+		if _, isPinned := pinned[key]; !ok || isPinned {
+			continue
+		}
+		// operators which cannot accept new clusters keep the clusters they already handle
+		if !targets[owner] && current[key] != owner {
+			continue
+		}
+		current[key] = owner
+		if owners[key] != owner {
+			moved = append(moved, key)
+		}
+	}
+	sort.Strings(moved)
+	return moved
+}
+
+// sorted clusters of every operator of the assignment, operators without clusters are left out
+func getRevisionOperators(assignment model.ShardAssignment) map[string][]string {
+	operators := make(map[string][]string)
+	for operatorIdentity, clusters := range assignment {
+		for _, cluster := range clusters {
+			operators[operatorIdentity] = append(operators[operatorIdentity], getClusterKey(cluster))
+		}
+		sort.Strings(operators[operatorIdentity])
+	}
+	return operators
+}
+
+// records the pushed assignment as a new revision when it differs from the latest one, no revision is
+// recorded while the assignment is frozen so that the revision rolled back to stays in the history, nor in
+// dry run mode as the assignment is not pushed
+func (sm *shardingManager) recordRevision(ctx context.Context, assignment model.ShardAssignment, resourceVersion string, now time.Time) {
+	if sm.revisionHandler == nil || sm.params.RevisionHistoryLimit <= 0 || sm.revisions.FrozenRevision != 0 || sm.params.DryRun {
+		return
+	}
+	revision := model.AssignmentRevision{
+		Revision:                1,
+		Time:                    now,
+		RegistryResourceVersion: resourceVersion,
+		Operators:               getRevisionOperators(assignment),
+	}
+	if len(sm.revisions.Revisions) > 0 {
+		latest := sm.revisions.Revisions[len(sm.revisions.Revisions)-1]
+		if maps.EqualFunc(latest.Operators, revision.Operators, slices.Equal[[]string]) {
+			return
+		}
+		revision.Revision = latest.Revision + 1
+	}
+	err := sm.revisionHandler.Record(ctx, revision, sm.params.RevisionHistoryLimit)
+	if err != nil {
+		logrus.Errorf("failed to record assignment revision %d: %v", revision.Revision, err)
+		return
+	}
+	sm.revisions.Revisions = append(sm.revisions.Revisions, revision)
+	if excess := len(sm.revisions.Revisions) - sm.params.RevisionHistoryLimit; excess > 0 {
+		sm.revisions.Revisions = sm.revisions.Revisions[excess:]
+	}
+}
+
+// revisions of the assignment kept in the history and the revision rebalancing is frozen at
+func (sm *shardingManager) GetRevisions() model.RevisionHistory {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	history := sm.revisions
+	history.Revisions = append([]model.AssignmentRevision{}, sm.revisions.Revisions...)
+	return history
+}
+
+// freezes the assignment at a revision of the history, it is applied by the next sync
+func (sm *shardingManager) Rollback(ctx context.Context, revision int) (model.AssignmentRevision, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.revisionHandler == nil {
+		return model.AssignmentRevision{}, fmt.Errorf("no revision history is kept")
+	}
+	if revision <= 0 {
+		return model.AssignmentRevision{}, fmt.Errorf("revision must be positive, got %d", revision)
+	}
+	err := sm.freeze(ctx, revision)
+	if err != nil {
+		return model.AssignmentRevision{}, err
+	}
+	frozen := sm.getFrozenRevision()
+	if frozen == nil {
+		return model.AssignmentRevision{}, fmt.Errorf("revision %d is no longer part of the revision history", revision)
+	}
+	return *frozen, nil
+}
+
+// resumes rebalancing frozen by a rollback
+func (sm *shardingManager) Resume(ctx context.Context) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.revisionHandler == nil {
+		return fmt.Errorf("no revision history is kept")
+	}
+	if sm.revisions.FrozenRevision == 0 {
+		return fmt.Errorf("rebalancing is not frozen")
+	}
+	return sm.freeze(ctx, 0)
+}
+
+func (sm *shardingManager) freeze(ctx context.Context, revision int) error {
+	err := sm.revisionHandler.Freeze(ctx, revision)
+	if err != nil {
+		return err
+	}
+	history, err := sm.revisionHandler.Get(ctx)
+	if err != nil {
+		return err
+	}
+	sm.updateRevisions(history)
+	return nil
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeriveShardConfigurationWithFrozenRevision(t *testing.T) {
+	revision := model.AssignmentRevision{
+		Revision:  1,
+		Operators: map[string][]string{"operator1": {"cluster1", "cluster2"}, "operator2": {"cluster3"}},
+	}
+	owners := map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator2"}
+	cordoned := getTestOperator("operator1", testNow)
+	cordoned.SchedulingState = model.CordonedSchedulingState
+	testCases := []struct {
+		name             string
+		frozen           int
+		operators        []model.Operator
+		overrides        []model.PlacementOverride
+		expectedOwners   map[string]string
+		expectedTriggers map[string]string
+	}{
+		{
+			name: "Given rebalancing which is not frozen, " +
+				"When shard configuration is derived, " +
+				"Then clusters should stay with their current operator",
+			operators:        []model.Operator{getTestOperator("operator1", testNow), getTestOperator("operator2", testNow)},
+			expectedOwners:   owners,
+			expectedTriggers: map[string]string{},
+		},
+		{
+			name: "Given an assignment frozen at a revision, " +
+				"When shard configuration is derived, " +
+				"Then clusters should be moved back to their operator of the revision",
+			frozen:           1,
+			operators:        []model.Operator{getTestOperator("operator1", testNow), getTestOperator("operator2", testNow)},
+			expectedOwners:   map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator2"},
+			expectedTriggers: map[string]string{"cluster1": model.RollbackAuditTrigger, "cluster2": model.RollbackAuditTrigger},
+		},
+		{
+			name: "Given an assignment frozen at a revision and a pinned cluster, " +
+				"When shard configuration is derived, " +
+				"Then the pinned cluster should stay where it is pinned",
+			frozen:           1,
+			operators:        []model.Operator{getTestOperator("operator1", testNow), getTestOperator("operator2", testNow)},
+			overrides:        []model.PlacementOverride{{Cluster: "cluster1", Operator: "operator2"}},
+			expectedOwners:   map[string]string{"cluster1": "operator2", "cluster2": "operator1", "cluster3": "operator2"},
+			expectedTriggers: map[string]string{"cluster1": model.OverrideAuditTrigger, "cluster2": model.RollbackAuditTrigger},
+		},
+		{
+			name: "Given an assignment frozen at a revision whose operator is cordoned, " +
+				"When shard configuration is derived, " +
+				"Then clusters should not be moved to the cordoned operator",
+			frozen:           1,
+			operators:        []model.Operator{cordoned, getTestOperator("operator2", testNow)},
+			expectedOwners:   owners,
+			expectedTriggers: map[string]string{},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, owners, map[string]string{})
+			sm.overrides = c.overrides
+			sm.revisions = model.RevisionHistory{Revisions: []model.AssignmentRevision{revision}, FrozenRevision: c.frozen}
+			assignment, reconciliation, err := sm.deriveShardConfiguration(c.operators, testNow)
+			if err != nil {
+				t.Fatalf("unexpected error while deriving shard configuration: %v", err)
+			}
+			actualOwners := getOwners(assignment)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+			if !cmp.Equal(reconciliation.triggers, c.expectedTriggers) {
+				t.Errorf(cmp.Diff(reconciliation.triggers, c.expectedTriggers))
+			}
+		})
+	}
+}
+
+func TestRecordRevision(t *testing.T) {
+	ctx := context.Background()
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.RevisionHistoryConfigMap = "admiral-sharding-revisions"
+	sm.params.RevisionHistoryLimit = 2
+	sm.revisionHandler = controller.NewRevisionHandler(model.Clients{KubernetesClient: fake.NewSimpleClientset()}, sm.params)
+	assignments := []model.ShardAssignment{
+		{"operator1": {getTestCluster("cluster1")}, "operator2": {getTestCluster("cluster2")}},
+		// unchanged assignment
+		{"operator2": {getTestCluster("cluster2")}, "operator1": {getTestCluster("cluster1")}},
+		{"operator1": {getTestCluster("cluster1"), getTestCluster("cluster2")}, "operator2": {}},
+		{"operator1": {getTestCluster("cluster1")}, "operator2": {getTestCluster("cluster2")}},
+	}
+	for _, assignment := range assignments {
+		sm.recordRevision(ctx, assignment, "1", testNow)
+	}
+	history, err := sm.revisionHandler.Get(ctx)
+	if err != nil {
+		t.Fatalf("failed to get revision history: %v", err)
+	}
+	expected := []model.AssignmentRevision{
+		{Revision: 2, Time: testNow, RegistryResourceVersion: "1", Operators: map[string][]string{"operator1": {"cluster1", "cluster2"}}},
+		{Revision: 3, Time: testNow, RegistryResourceVersion: "1", Operators: map[string][]string{"operator1": {"cluster1"}, "operator2": {"cluster2"}}},
+	}
+	if !cmp.Equal(history.Revisions, expected) {
+		t.Errorf(cmp.Diff(history.Revisions, expected))
+	}
+	if !cmp.Equal(sm.revisions.Revisions, expected) {
+		t.Errorf(cmp.Diff(sm.revisions.Revisions, expected))
+	}
+
+	// no revision is recorded while the assignment is frozen
+	_, err = sm.Rollback(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	sm.recordRevision(ctx, assignments[2], "2", testNow)
+	if len(sm.revisions.Revisions) != 2 || sm.revisions.FrozenRevision != 2 {
+		t.Errorf("expected history to be kept while frozen at revision 2, got %+v", sm.revisions)
+	}
+}
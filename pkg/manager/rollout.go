@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/monitoring"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/schedule"
+	"github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+var (
+	rolloutMovedClusters = monitoring.NewGauge(
+		"rollout_moved_clusters",
+		"number of clusters moved by the current or last rollout",
+		monitoring.WithMeter(shardingManagerMeter))
+	rolloutPendingClusters = monitoring.NewGauge(
+		"rollout_pending_clusters",
+		"number of cluster moves waiting for a later rollout wave or rebalance window",
+		monitoring.WithMeter(shardingManagerMeter))
+	rolloutPaused = monitoring.NewGauge(
+		"rollout_paused",
+		"1 when the rollout is paused as operators report errors, 0 otherwise",
+		monitoring.WithMeter(shardingManagerMeter))
+)
+
+// moves for a specific reason are applied at once, failed operators cannot keep their clusters and drains
+// are limited by the drain batch size, rollbacks restore a previous assignment at once
+func isRateLimitedMove(trigger string) bool {
+	switch trigger {
+	case model.FailoverAuditTrigger, model.OverrideAuditTrigger, model.DrainAuditTrigger, model.RollbackAuditTrigger:
+		return false
+	}
+	return true
+}
+
+// caps the number of clusters moved between operators which can still handle them to the configured number
+// of moves per rollout interval. Moves beyond the cap are queued and applied by later waves, no moves are
+// applied while operators report errors on their shards, outside of rebalance windows or while the assignment
+// is frozen after a rollback. Returns the
+// assignment to push, in which clusters whose move is queued are kept by their current operator, along with
+// the resulting rollout status
+func (sm *shardingManager) limitRollout(
+	ctx context.Context,
+	assignment model.ShardAssignment,
+	triggers map[string]string,
+	operators []model.Operator,
+	available []model.Operator,
+	now time.Time) (model.ShardAssignment, model.RolloutStatus) {
+	status := sm.rollout
+	status.Pending = []model.RolloutMove{}
+	if sm.params.RolloutMaxMoves <= 0 && len(sm.windows) == 0 && sm.revisions.FrozenRevision == 0 {
+		return assignment, model.RolloutStatus{Pending: []model.RolloutMove{}}
+	}
+	status.FrozenRevision = sm.revisions.FrozenRevision
+	status.OutsideWindow, status.NextWindow = !inRebalanceWindow(sm.windows, now), time.Time{}
+	if status.OutsideWindow {
+		status.NextWindow = getNextRebalanceWindow(sm.windows, now)
+	}
+	var (
+		owners  = getOwners(assignment)
+		health  = getOperatorsHealth(operators, now, sm.params.OperatorGracePeriod)
+		targets = make(map[string]bool)
+		queued  = make(map[string]bool)
+		moves   []model.RolloutMove
+		fresh   []model.RolloutMove
+	)
+	for _, operator := range available {
+		targets[operator.Identity] = true
+	}
+	// queued moves are kept as long as the cluster stays with the operator it was queued on
+	for _, move := range sm.rollout.Pending {
+		if owners[move.Cluster] == move.From && targets[move.To] {
+			moves = append(moves, move)
+			queued[move.Cluster] = true
+		}
+	}
+	for key, to := range owners {
+		from, ok := sm.owners[key]
+		if !ok || from == to || queued[key] || !isRateLimitedMove(triggers[key]) {
+			continue
+		}
+		if state, discovered := health[from]; !discovered || state == operatorFailed {
+			continue
+		}
+		fresh = append(fresh, model.RolloutMove{Cluster: key, From: from, To: to})
+	}
+	sort.Slice(fresh, func(i, j int) bool {
+		return fresh[i].Cluster < fresh[j].Cluster
+	})
+	moves = append(moves, fresh...)
+	if len(moves) == 0 {
+		if len(sm.rollout.Pending) > 0 {
+			logrus.Infof("rollout completed after moving %d clusters", status.Moved)
+		}
+		status.Paused, status.PausedReason = false, ""
+		return assignment, status
+	}
+
+	if len(sm.rollout.Pending) == 0 {
+		status.Started, status.Moved = now, 0
+	}
+	if now.Sub(status.WaveStarted) >= sm.params.RolloutInterval {
+		status.WaveStarted, status.WaveMoves = now, 0
+	}
+	// errors are only checked once moves can be applied
+	if !status.OutsideWindow && status.FrozenRevision == 0 {
+		status.Paused, status.PausedReason = sm.checkRolloutPause(ctx, status.Paused)
+	}
+	budget := len(moves)
+	switch {
+	case status.Paused || status.OutsideWindow || status.FrozenRevision != 0:
+		budget = 0
+	case sm.params.RolloutMaxMoves > 0:
+		budget = max(sm.params.RolloutMaxMoves-status.WaveMoves, 0)
+	}
+	released := min(budget, len(moves))
+	for _, move := range moves[:released] {
+		owners[move.Cluster] = move.To
+		if queued[move.Cluster] {
+			triggers[move.Cluster] = model.RolloutAuditTrigger
+		}
+	}
+	for _, move := range moves[released:] {
+		owners[move.Cluster] = move.From
+	}
+	status.WaveMoves += released
+	status.Moved += released
+	status.Pending = append(status.Pending, moves[released:]...)
+	if status.FrozenRevision != 0 {
+		logrus.Infof("queued %d optional cluster moves while the assignment is frozen at revision %d",
+			len(status.Pending), status.FrozenRevision)
+	} else if status.OutsideWindow {
+		logrus.Infof("queued %d optional cluster moves until the next rebalance window opens at %s",
+			len(status.Pending), status.NextWindow.Format(time.RFC3339))
+	} else if len(status.Pending) > 0 {
+		logrus.Infof("moved %d clusters in rollout wave started at %s, %d cluster moves are queued for later waves",
+			released, status.WaveStarted.Format(time.RFC3339), len(status.Pending))
+	}
+	return reassignClusters(assignment, owners), status
+}
+
+// checks whether operators report errors on their shards, the rollout is paused as well when shards cannot
+// be listed. Returns whether the rollout is paused and why
+func (sm *shardingManager) checkRolloutPause(ctx context.Context, paused bool) (bool, string) {
+	var reason string
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		reason = fmt.Sprintf("failed to list shards: %v", err)
+	} else if failing := getFailingShards(shards); len(failing) > 0 {
+		reason = fmt.Sprintf("operators report errors on shards %s", strings.Join(failing, ", "))
+	}
+	switch {
+	case reason != "" && !paused:
+		message := fmt.Sprintf("paused rollout of cluster moves: %s", reason)
+		logrus.Warn(message)
+		sm.recordEvent(sm.reference, coreV1.EventTypeWarning, rolloutPausedReason, message)
+	case reason == "" && paused:
+		message := "resumed rollout of cluster moves as operators no longer report errors"
+		logrus.Info(message)
+		sm.recordEvent(sm.reference, coreV1.EventTypeNormal, rolloutResumedReason, message)
+	}
+	return reason != "", reason
+}
+
+// whether optional moves can be applied, they always can when no rebalance window is configured. Windows are
+// evaluated in UTC
+func inRebalanceWindow(windows []schedule.Window, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, window := range windows {
+		if window.Contains(now.UTC()) {
+			return true
+		}
+	}
+	return false
+}
+
+// earliest time a rebalance window opens after the provided time, zero when none opens within a year
+func getNextRebalanceWindow(windows []schedule.Window, now time.Time) time.Time {
+	var next time.Time
+	for _, window := range windows {
+		start, ok := window.Next(now.UTC())
+		if ok && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
+
+// sorted names of shards whose last reported condition is an error
+func getFailingShards(shards []typeV1.Shard) []string {
+	var failing []string
+	for _, shard := range shards {
+		var last *typeV1.ShardStatusCondition
+		for i, condition := range shard.Status.Conditions {
+			if last == nil || condition.LastUpdatedTime.After(last.LastUpdatedTime.Time) {
+				last = &shard.Status.Conditions[i]
+			}
+		}
+		if last == nil {
+			continue
+		}
+		if (last.Type == typeV1.SyncFailed && last.Status == typeV1.TrueConditionStatus) || last.Reason == typeV1.ErrorOccurred {
+			failing = append(failing, shard.Name)
+		}
+	}
+	sort.Strings(failing)
+	return failing
+}
+
+// assigns the clusters of the assignment to the provided owners, operators keep their entry even when they
+// are left without clusters
+func reassignClusters(assignment model.ShardAssignment, owners map[string]string) model.ShardAssignment {
+	var identities []string
+	reassigned := make(model.ShardAssignment)
+	for operatorIdentity := range assignment {
+		identities = append(identities, operatorIdentity)
+		reassigned[operatorIdentity] = []registry.ClusterConfig{}
+	}
+	sort.Strings(identities)
+	for _, operatorIdentity := range identities {
+		for _, cluster := range assignment[operatorIdentity] {
+			owner := owners[getClusterKey(cluster)]
+			reassigned[owner] = append(reassigned[owner], cluster)
+		}
+	}
+	return reassigned
+}
+
+// reports progress of the rollout as metrics
+func reportRolloutProgress(status model.RolloutStatus) {
+	rolloutMovedClusters.Set(int64(status.Moved))
+	rolloutPendingClusters.Set(int64(len(status.Pending)))
+	var paused int64
+	if status.Paused {
+		paused = 1
+	}
+	rolloutPaused.Set(paused)
+}
+
+// progress of the current or last rollout along with the cluster moves queued for later waves
+func (sm *shardingManager) GetRolloutStatus() model.RolloutStatus {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	status := sm.rollout
+	status.Pending = append([]model.RolloutMove{}, sm.rollout.Pending...)
+	return status
+}
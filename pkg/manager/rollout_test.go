@@ -0,0 +1,244 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/schedule"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestLimitRollout(t *testing.T) {
+	assignment := model.ShardAssignment{
+		"operator1": {},
+		"operator2": {getTestCluster("cluster1"), getTestCluster("cluster2"), getTestCluster("cluster3")},
+	}
+	failingShard := &typeV1.Shard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.GetShardName("operator2", 0),
+			Namespace: "shard-namespace",
+			Labels:    map[string]string{controller.ShardIdentity: "dev", testOperatorIdentityLabel: "operator2"},
+		},
+		Status: typeV1.ShardStatus{Conditions: []typeV1.ShardStatusCondition{
+			{Type: typeV1.SyncComplete, Status: typeV1.TrueConditionStatus, Reason: typeV1.Processed, LastUpdatedTime: metav1.NewTime(testNow.Add(-time.Minute))},
+			{Type: typeV1.SyncFailed, Status: typeV1.TrueConditionStatus, Reason: typeV1.ErrorOccurred, LastUpdatedTime: metav1.NewTime(testNow.Add(-time.Second))},
+		}},
+	}
+	// clusters whose move is queued are kept by their current operator by the distribution
+	queuedAssignment := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster3")},
+		"operator2": {getTestCluster("cluster1"), getTestCluster("cluster2")},
+	}
+	queued := model.RolloutStatus{
+		Started:     testNow.Add(-30 * time.Second),
+		Moved:       2,
+		Pending:     []model.RolloutMove{{Cluster: "cluster3", From: "operator1", To: "operator2"}},
+		WaveStarted: testNow.Add(-30 * time.Second),
+		WaveMoves:   2,
+	}
+	testCases := []struct {
+		name             string
+		assignment       model.ShardAssignment
+		maxMoves         int
+		windows          string
+		frozen           int
+		owners           map[string]string
+		rollout          model.RolloutStatus
+		triggers         map[string]string
+		shards           []*typeV1.Shard
+		expectedPushed   map[string][]string
+		expectedMoved    int
+		expectedPending  []string
+		expectedTriggers map[string]string
+		expectedReasons  []string
+	}{
+		{
+			name: "Given no rollout limit, " +
+				"When clusters are moved between healthy operators, " +
+				"Then every cluster should be moved at once",
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			expectedPushed:   map[string][]string{"operator2": {"cluster1", "cluster2", "cluster3"}},
+			expectedTriggers: map[string]string{},
+		},
+		{
+			name: "Given a rollout limit, " +
+				"When more clusters are moved than allowed, " +
+				"Then remaining moves should be queued for a later wave",
+			maxMoves:         2,
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			expectedPushed:   map[string][]string{"operator1": {"cluster3"}, "operator2": {"cluster1", "cluster2"}},
+			expectedMoved:    2,
+			expectedPending:  []string{"cluster3"},
+			expectedTriggers: map[string]string{},
+		},
+		{
+			name: "Given a rollout limit, " +
+				"When clusters are moved away from a failed operator, " +
+				"Then the moves should not be limited",
+			maxMoves:         1,
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			triggers:         map[string]string{"cluster1": model.FailoverAuditTrigger},
+			expectedPushed:   map[string][]string{"operator1": {"cluster3"}, "operator2": {"cluster1", "cluster2"}},
+			expectedMoved:    1,
+			expectedPending:  []string{"cluster3"},
+			expectedTriggers: map[string]string{"cluster1": model.FailoverAuditTrigger},
+		},
+		{
+			name: "Given a queued move, " +
+				"When the current wave already moved as many clusters as allowed, " +
+				"Then the move should stay queued",
+			assignment:       queuedAssignment,
+			maxMoves:         2,
+			owners:           map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator1"},
+			rollout:          queued,
+			expectedPushed:   map[string][]string{"operator1": {"cluster3"}, "operator2": {"cluster1", "cluster2"}},
+			expectedMoved:    2,
+			expectedPending:  []string{"cluster3"},
+			expectedTriggers: map[string]string{},
+		},
+		{
+			name: "Given a queued move, " +
+				"When the next wave starts, " +
+				"Then the move should be applied",
+			assignment:       queuedAssignment,
+			maxMoves:         2,
+			owners:           map[string]string{"cluster1": "operator2", "cluster2": "operator2", "cluster3": "operator1"},
+			rollout:          func() model.RolloutStatus { r := queued; r.WaveStarted = testNow.Add(-2 * time.Minute); return r }(),
+			expectedPushed:   map[string][]string{"operator2": {"cluster1", "cluster2", "cluster3"}},
+			expectedMoved:    3,
+			expectedTriggers: map[string]string{"cluster3": model.RolloutAuditTrigger},
+		},
+		{
+			name: "Given a rebalance window, " +
+				"When clusters are moved outside of the window, " +
+				"Then the moves should be queued",
+			windows:          "0 2 * * * 1h",
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			triggers:         map[string]string{"cluster1": model.FailoverAuditTrigger},
+			expectedPushed:   map[string][]string{"operator1": {"cluster2", "cluster3"}, "operator2": {"cluster1"}},
+			expectedPending:  []string{"cluster2", "cluster3"},
+			expectedTriggers: map[string]string{"cluster1": model.FailoverAuditTrigger},
+		},
+		{
+			name: "Given a rebalance window, " +
+				"When clusters are moved within the window, " +
+				"Then every move should be applied",
+			windows:          "0 2 * * * 1h; 0 18 * * * 1h",
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			expectedPushed:   map[string][]string{"operator2": {"cluster1", "cluster2", "cluster3"}},
+			expectedMoved:    3,
+			expectedTriggers: map[string]string{},
+		},
+		{
+			name: "Given an assignment frozen at a revision, " +
+				"When clusters are moved between healthy operators, " +
+				"Then every move should be queued until rebalancing is resumed",
+			frozen:           1,
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			shards:           []*typeV1.Shard{failingShard},
+			expectedPushed:   map[string][]string{"operator1": {"cluster1", "cluster2", "cluster3"}},
+			expectedPending:  []string{"cluster1", "cluster2", "cluster3"},
+			expectedTriggers: map[string]string{},
+		},
+		{
+			name: "Given an operator reporting errors on its shard, " +
+				"When clusters are moved, " +
+				"Then the rollout should be paused",
+			maxMoves:         2,
+			owners:           map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator1"},
+			shards:           []*typeV1.Shard{failingShard},
+			expectedPushed:   map[string][]string{"operator1": {"cluster1", "cluster2", "cluster3"}},
+			expectedPending:  []string{"cluster1", "cluster2", "cluster3"},
+			expectedTriggers: map[string]string{},
+			expectedReasons:  []string{rolloutPausedReason},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			for _, shard := range c.shards {
+				_, err := client.AdmiralV1().Shards(shard.Namespace).Create(context.Background(), shard, metav1.CreateOptions{})
+				if err != nil {
+					t.Fatalf("failed to create shard: %v", err)
+				}
+			}
+			sm := getTestShardingManager(model.FailbackRecoveryPolicy, c.owners, map[string]string{})
+			sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+			sm.params.ShardNamespace = "shard-namespace"
+			sm.params.ShardingManagerIdentity = "dev"
+			sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+			sm.params.RolloutMaxMoves = c.maxMoves
+			sm.params.RolloutInterval = time.Minute
+			sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: client.AdmiralV1()}, sm.params)
+			sm.rollout = c.rollout
+			windows, err := schedule.ParseWindows(c.windows)
+			if err != nil {
+				t.Fatalf("failed to parse windows: %v", err)
+			}
+			sm.windows = windows
+			sm.revisions.FrozenRevision = c.frozen
+			triggers := make(map[string]string)
+			for cluster, trigger := range c.triggers {
+				triggers[cluster] = trigger
+			}
+			operators := []model.Operator{getTestOperator("operator1", testNow), getTestOperator("operator2", testNow)}
+
+			if c.assignment == nil {
+				c.assignment = assignment
+			}
+
+			pushed, status := sm.limitRollout(context.Background(), c.assignment, triggers, operators, operators, testNow)
+			actualPushed := make(map[string][]string)
+			for operatorIdentity, clusters := range pushed {
+				for _, cluster := range clusters {
+					actualPushed[operatorIdentity] = append(actualPushed[operatorIdentity], cluster.Name)
+				}
+				sort.Strings(actualPushed[operatorIdentity])
+			}
+			if !cmp.Equal(actualPushed, c.expectedPushed) {
+				t.Errorf(cmp.Diff(actualPushed, c.expectedPushed))
+			}
+			if status.Moved != c.expectedMoved {
+				t.Errorf("expected %d moved clusters, got %d", c.expectedMoved, status.Moved)
+			}
+			var actualPending []string
+			for _, move := range status.Pending {
+				actualPending = append(actualPending, move.Cluster)
+			}
+			if !cmp.Equal(actualPending, c.expectedPending) {
+				t.Errorf(cmp.Diff(actualPending, c.expectedPending))
+			}
+			if !cmp.Equal(triggers, c.expectedTriggers) {
+				t.Errorf(cmp.Diff(triggers, c.expectedTriggers))
+			}
+			actualReasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+			if !cmp.Equal(actualReasons, c.expectedReasons) {
+				t.Errorf(cmp.Diff(actualReasons, c.expectedReasons))
+			}
+		})
+	}
+}
+
+func TestReassignClusters(t *testing.T) {
+	assignment := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster1"), getTestCluster("cluster2")},
+		"operator2": {},
+	}
+	expected := model.ShardAssignment{
+		"operator1": {getTestCluster("cluster1")},
+		"operator2": {getTestCluster("cluster2")},
+	}
+	actual := reassignClusters(assignment, map[string]string{"cluster1": "operator1", "cluster2": "operator2"})
+	if !cmp.Equal(actual, expected) {
+		t.Errorf(cmp.Diff(actual, expected))
+	}
+}
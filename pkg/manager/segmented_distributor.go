@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+)
+
+// partitions clusters by the value of a metadata key and distributes each partition amongst the pool of
+// operators labelled with the same segment, so that an incident in one segment's pool cannot move clusters
+// of that segment to another pool. Clusters without a segment are handled by operators without a segment
+type segmentedDistributor struct {
+	key         string
+	distributor *leastLoadedDistributor
+}
+
+func NewSegmentedDistributor(key string) *segmentedDistributor {
+	return &segmentedDistributor{key: key, distributor: NewLeastLoadedDistributor()}
+}
+
+func (d *segmentedDistributor) Distribute(clusters []registry.ClusterConfig, operators []model.Operator, current map[string]string) (model.ShardAssignment, error) {
+	pools := &poolDistributor{
+		kind: "segment",
+		clusterPool: func(cluster registry.ClusterConfig) string {
+			return cluster.Metadata.Get(d.key)
+		},
+		operatorPool: func(operator model.Operator) string {
+			return operator.Segment
+		},
+		distributor: d.distributor,
+	}
+	return pools.Distribute(clusters, operators, current)
+}
@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/fake"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func getTestSegmentCluster(name string, segment string, identities ...string) registry.ClusterConfig {
+	cluster := getTestCluster(name, identities...)
+	cluster.Metadata = registry.ClusterMetadata{Segment: segment}
+	return cluster
+}
+
+func TestSegmentedDistributor(t *testing.T) {
+	clusters := []registry.ClusterConfig{
+		getTestSegmentCluster("cluster1", "payments", "identity1"),
+		getTestSegmentCluster("cluster2", "payments", "identity2"),
+		getTestSegmentCluster("cluster3", "tax", "identity3"),
+		getTestSegmentCluster("cluster4", "", "identity4"),
+	}
+	testCases := []struct {
+		name           string
+		operators      []model.Operator
+		current        map[string]string
+		expectedOwners map[string]string
+	}{
+		{
+			name: "Given an operator pool for every segment, " +
+				"When clusters are distributed, " +
+				"Then clusters should only be assigned to operators of their segment",
+			operators: []model.Operator{
+				{Identity: "operator1", Segment: "payments"},
+				{Identity: "operator2", Segment: "payments"},
+				{Identity: "operator3", Segment: "tax"},
+				{Identity: "operator4"},
+			},
+			current:        map[string]string{},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator2", "cluster3": "operator3", "cluster4": "operator4"},
+		},
+		{
+			name: "Given a cluster owned by an operator of another segment, " +
+				"When clusters are distributed, " +
+				"Then the cluster should be moved to an operator of its segment",
+			operators: []model.Operator{
+				{Identity: "operator1", Segment: "payments"},
+				{Identity: "operator3", Segment: "tax"},
+				{Identity: "operator4"},
+			},
+			current:        map[string]string{"cluster1": "operator3", "cluster3": "operator3"},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator1", "cluster3": "operator3", "cluster4": "operator4"},
+		},
+		{
+			name: "Given a segment without available operators and clusters with a current operator, " +
+				"When clusters are distributed, " +
+				"Then clusters should stay with their current operator instead of being dropped",
+			operators: []model.Operator{
+				{Identity: "operator3", Segment: "tax"},
+				{Identity: "operator4"},
+			},
+			current:        map[string]string{"cluster1": "operator1", "cluster2": "operator3"},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster2": "operator3", "cluster3": "operator3", "cluster4": "operator4"},
+		},
+		{
+			name: "Given a segment without available operators and a cluster without a current operator, " +
+				"When clusters are distributed, " +
+				"Then the cluster should be left unassigned while other segments are still distributed",
+			operators: []model.Operator{
+				{Identity: "operator3", Segment: "tax"},
+				{Identity: "operator4"},
+			},
+			current:        map[string]string{"cluster1": "operator1"},
+			expectedOwners: map[string]string{"cluster1": "operator1", "cluster3": "operator3", "cluster4": "operator4"},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			assignment, err := NewSegmentedDistributor("segment").Distribute(clusters, c.operators, c.current)
+			if err != nil {
+				t.Fatalf("unexpected error while distributing clusters: %v", err)
+			}
+			actualOwners := getOwners(assignment)
+			if !cmp.Equal(actualOwners, c.expectedOwners) {
+				t.Errorf(cmp.Diff(actualOwners, c.expectedOwners))
+			}
+		})
+	}
+}
+
+func TestBulkSyncSegmentWithoutOperators(t *testing.T) {
+	ctx := context.Background()
+	sm := getTestShardingManager(model.FailbackRecoveryPolicy, map[string]string{}, map[string]string{})
+	sm.params.ShardNamespace = "shard-namespace"
+	sm.params.ShardingManagerIdentity = "dev"
+	sm.params.OperatorIdentityLabel = testOperatorIdentityLabel
+	sm.reference = &coreV1.ObjectReference{Kind: "Pod", Namespace: "admiral", Name: "sharding-manager"}
+	sm.loadDistributor = NewSegmentedDistributor("segment")
+	sm.shardHandler = controller.NewShardHandler(model.Clients{AdmiralClient: fake.NewSimpleClientset().AdmiralV1()}, sm.params)
+	sm.registryClient = &testRegistryClient{
+		clusters: []registry.ClusterConfig{
+			getTestSegmentCluster("cluster1", "payments", "identity1"),
+			getTestSegmentCluster("cluster3", "tax", "identity3"),
+		},
+		resourceVersion: "1",
+	}
+	operator := getTestOperator("operator3", time.Now())
+	operator.Segment = "tax"
+	sm.operatorHandler = &testOperatorHandler{operators: []model.Operator{operator}}
+	metric := &testMetric{name: "unassigned_clusters"}
+	previousUnassigned := unassignedClusters
+	unassignedClusters = metric
+	t.Cleanup(func() {
+		unassignedClusters = previousUnassigned
+	})
+
+	// the payments pool has no operator, its new cluster must not prevent the tax segment from being distributed
+	err := sm.bulkSync(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while syncing: %v", err)
+	}
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list shards: %v", err)
+	}
+	expectedOwners := map[string]string{"cluster3": "operator3"}
+	actualOwners := getOwnersFromShards(shards, sm.params)
+	if !cmp.Equal(actualOwners, expectedOwners) {
+		t.Errorf(cmp.Diff(actualOwners, expectedOwners))
+	}
+	if !cmp.Equal(sm.unassigned, []string{"cluster1"}) {
+		t.Errorf(cmp.Diff(sm.unassigned, []string{"cluster1"}))
+	}
+	if metric.measurements == 0 {
+		t.Errorf("expected unassigned clusters to be measured")
+	}
+	reasons := getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder))
+	unassignedEvents := 0
+	for _, reason := range reasons {
+		if reason == clustersUnassignedReason {
+			unassignedEvents++
+		}
+	}
+	if unassignedEvents != 1 {
+		t.Errorf("expected one %s event, got %v", clustersUnassignedReason, reasons)
+	}
+
+	// clusters which stay unassigned are not reported again
+	err = sm.bulkSync(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error while syncing: %v", err)
+	}
+	for _, reason := range getRecordedReasons(sm.eventRecorder.(*record.FakeRecorder)) {
+		if reason == clustersUnassignedReason {
+			t.Errorf("expected cluster1 not to be reported again")
+		}
+	}
+}
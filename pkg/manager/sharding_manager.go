@@ -2,14 +2,22 @@ package manager
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
+	typeV1 "github.com/istio-ecosystem/admiral-api/pkg/apis/admiral/v1"
 	admiralV1 "github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/typed/admiral/v1"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/schedule"
 	"github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 )
 
 type shardingManager struct {
@@ -17,28 +25,105 @@ type shardingManager struct {
 	registryClient   registry.RegistryConfigInterface
 	cache            model.ShardingMangerCache
 	shardHandler     controller.ShardInterface
-	identity         string
+	operatorHandler  controller.OperatorInterface
+	overrideHandler  controller.OverrideInterface
+	revisionHandler  controller.RevisionInterface
+	loadDistributor  LoadDistributor
+	eventRecorder    record.EventRecorder
+	// object events which do not relate to a shard, operator or override are recorded on
+	reference *coreV1.ObjectReference
+	params    *model.ShardingManagerParams
+	identity  string
+	mutex     sync.Mutex
+	// operator currently handling each cluster
+	owners map[string]string
+	// operator each failed over cluster was moved away from
+	failedOver map[string]string
+	// last valid placement overrides and the outcome of applying them
+	overrides      []model.PlacementOverride
+	overrideStatus model.OverrideStatus
+	auditLog       *auditLog
+	// clusters handed off between operators keyed by cluster
+	migrations map[string]model.Migration
+	// registry configuration rejected by the blast radius guard and removals accepted through the admin api
+	rejectedChange   *model.RegistryChange
+	acceptedRemovals map[string]bool
+	// progress of the rollout of cluster moves capped per interval and restricted to rebalance windows
+	rollout model.RolloutStatus
+	windows []schedule.Window
+	// last loaded revisions of the assignment and the revision it is frozen at after a rollback
+	revisions model.RevisionHistory
+	// shard changes the last sync would have made in dry run mode
+	dryRun model.DryRunStatus
+	// clusters left unassigned by the last sync as no operator of their pool is available
+	unassigned []string
 }
 
 func NewShardingManager(
 	ctx context.Context,
 	shardHandler controller.ShardInterface,
+	operatorHandler controller.OperatorInterface,
+	overrideHandler controller.OverrideInterface,
+	revisionHandler controller.RevisionInterface,
 	client model.Clients,
-	identity string) (*shardingManager, error) {
+	params *model.ShardingManagerParams) (*shardingManager, error) {
+	loadDistributor, err := NewLoadDistributor(params)
+	if err != nil {
+		return nil, err
+	}
+	auditLog, err := newAuditLog(params.AuditLogPath, params.AuditLogSize)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := schedule.ParseWindows(params.RebalanceWindows)
+	if err != nil {
+		return nil, err
+	}
+	// no events are recorded in dry run mode as they would report changes which are not made
+	eventRecorder := client.EventRecorder
+	if params.DryRun {
+		eventRecorder = nil
+	}
 	return &shardingManager{
 		cache: model.ShardingMangerCache{
 			ClusterCache: []registry.ClusterConfig{},
 		},
 		admiralAPIClient: client.AdmiralClient,
 		registryClient:   client.RegistryClient,
+		eventRecorder:    eventRecorder,
+		reference:        getManagerReference(),
 		shardHandler:     shardHandler,
-		identity:         identity,
+		operatorHandler:  operatorHandler,
+		overrideHandler:  overrideHandler,
+		revisionHandler:  revisionHandler,
+		loadDistributor:  loadDistributor,
+		auditLog:         auditLog,
+		windows:          windows,
+		params:           params,
+		identity:         params.ShardingManagerIdentity,
+		owners:           make(map[string]string),
+		failedOver:       make(map[string]string),
+		migrations:       make(map[string]model.Migration),
+		rollout:          model.RolloutStatus{Pending: []model.RolloutMove{}},
+		revisions:        model.RevisionHistory{Revisions: []model.AssignmentRevision{}},
+		dryRun:           model.DryRunStatus{Enabled: params.DryRun, Changes: []model.DryRunChange{}},
+		overrideStatus: model.OverrideStatus{
+			Applied:  []model.PlacementOverride{},
+			Rejected: []model.RejectedOverride{},
+		},
 	}, nil
 }
 
 func (sm *shardingManager) Start(ctx context.Context) error {
+	// clusters stay with the operator handling them in the existing shards rather than being distributed from scratch
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		logrus.Warnf("failed to read current assignment from shards, distributing clusters from scratch: %v", err)
+	} else {
+		sm.loadLiveAssignment(shards)
+	}
 	// Bulk sync initial configurations
-	err := sm.bulkSync(ctx)
+	err = sm.bulkSync(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to bulk sync configurations: %v", err)
 	}
@@ -47,61 +132,319 @@ func (sm *shardingManager) Start(ctx context.Context) error {
 	return nil
 }
 
-func (sm *shardingManager) pushShardConfiguration(ctx context.Context, config model.ShardingMangerCache) error {
-	_, err := sm.shardHandler.Create(ctx, config.ClusterCache, "identity", "operatorIdentity")
+// creates or updates the shards of every operator in the assignment and removes shards which are no longer
+// part of it, either because the operator is no longer discovered or because it needs fewer shards
+func (sm *shardingManager) pushShardConfiguration(ctx context.Context, assignment model.ShardAssignment) error {
+	var (
+		desired    = make(map[string]bool)
+		live       = make(map[string]typeV1.Shard)
+		identities []string
+	)
+	// shards are listed before pushing so that changed shards can be told apart from unchanged ones
+	shards, err := sm.shardHandler.List(ctx)
 	if err != nil {
-		logrus.Warnf("error creating shard: %v", err)
-		if errors.IsAlreadyExists(err) {
-			logrus.Info("shard already exists, updating it...")
-			_, err = sm.shardHandler.Update(ctx, config.ClusterCache, "identity", "operatorIdentity")
-			return err
+		return err
+	}
+	for _, shard := range shards {
+		live[shard.Name] = shard
+	}
+	for operatorIdentity := range assignment {
+		identities = append(identities, operatorIdentity)
+	}
+	sort.Strings(identities)
+	// a failing shard does not prevent the shards of other operators, possibly on other shard targets, from being pushed
+	var errs []error
+	for _, operatorIdentity := range identities {
+		for index, clusters := range sm.shardHandler.Partition(assignment[operatorIdentity], operatorIdentity) {
+			shardName := controller.GetShardName(operatorIdentity, index)
+			desired[shardName] = true
+			shard, err := sm.shardHandler.Create(ctx, clusters, shardName, operatorIdentity)
+			if err == nil {
+				sm.recordEvent(shardReference(shard), coreV1.EventTypeNormal, shardCreatedReason,
+					fmt.Sprintf("created shard of operator %s with %d clusters", operatorIdentity, len(clusters)))
+				continue
+			}
+			if !errors.IsAlreadyExists(err) {
+				sm.reportShardSyncFailure(shardName, err)
+				errs = append(errs, err)
+				continue
+			}
+			logrus.Infof("shard %s already exists, updating it...", shardName)
+			shard, err = sm.shardHandler.Update(ctx, clusters, shardName, operatorIdentity)
+			if err != nil {
+				sm.reportShardSyncFailure(shardName, err)
+				errs = append(errs, err)
+				continue
+			}
+			liveShard, ok := live[shardName]
+			if shard != nil && (!ok || len(DiffShards([]*typeV1.Shard{shard}, []typeV1.Shard{liveShard}, sm.params.OperatorIdentityLabel)) > 0) {
+				sm.recordEvent(shardReference(shard), coreV1.EventTypeNormal, shardUpdatedReason,
+					fmt.Sprintf("updated shard of operator %s to %d clusters", operatorIdentity, len(clusters)))
+			}
 		}
 	}
-	return err
+
+	for i := range shards {
+		if desired[shards[i].Name] {
+			continue
+		}
+		logrus.Infof("deleting shard %s which is no longer part of the assignment", shards[i].Name)
+		err = sm.shardHandler.Delete(ctx, &shards[i])
+		if err != nil {
+			sm.reportShardSyncFailure(shards[i].Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		sm.recordEvent(shardReference(&shards[i]), coreV1.EventTypeNormal, shardDeletedReason,
+			"deleted shard which is no longer part of the assignment")
+	}
+	return goerrors.Join(errs...)
+}
+
+// builds the shards the assignment would be pushed as without changing the live shards, which are returned
+// along with them. Skipped changes are reported in dry run mode and clusters stay with the operator handling
+// them in the live shards
+func (sm *shardingManager) previewShardConfiguration(ctx context.Context, assignment model.ShardAssignment) ([]*typeV1.Shard, []typeV1.Shard, error) {
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sm.params.DryRun {
+		sm.reportDryRun(assignment, shards, time.Now())
+	}
+	sm.owners = getOwnersFromShards(shards, sm.params)
+	return controller.BuildShardResources(assignment, sm.params), shards, nil
 }
 
+func (sm *shardingManager) reportShardSyncFailure(shardName string, err error) {
+	logrus.Errorf("failed to sync shard %s: %v", shardName, err)
+	shard := &typeV1.Shard{}
+	shard.Name = shardName
+	shard.Namespace = sm.params.ShardNamespace
+	sm.recordEvent(shardReference(shard), coreV1.EventTypeWarning, shardSyncFailedReason, err.Error())
+}
+
+// shards are only pushed when not running in dry run mode
 func (sm *shardingManager) bulkSync(ctx context.Context) error {
-	var (
-		cache []registry.ClusterConfig
-		err   error
-	)
-	cache, err = sm.registryConfigSyncer(ctx)
+	_, _, err := sm.sync(ctx, false)
+	return err
+}
+
+// loads registry configuration, distributes it amongst discovered operators subject to the blast radius guard,
+// the rollout limits, rebalance windows and handoffs, and pushes the resulting shards. When previewing or in
+// dry run mode no shard is changed and nothing is recorded, the shards which would be pushed are returned along
+// with the live shards instead. Settings are read once under the lock, which is held until the sync completes,
+// so that a reload waits for the sync and the whole sync runs with the same settings
+func (sm *shardingManager) sync(ctx context.Context, preview bool) ([]*typeV1.Shard, []typeV1.Shard, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	params := sm.params
+	push := !preview && !params.DryRun
+
+	cache, resourceVersion, err := sm.registryConfigSyncer(ctx)
 	if err != nil {
-		return err
+		sm.recordEvent(sm.reference, coreV1.EventTypeWarning, registrySyncFailedReason,
+			fmt.Sprintf("failed to load configuration from registry, keeping current shards: %v", err))
+		return nil, nil, err
+	}
+	operators, err := sm.operatorHandler.List(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to discover operators: %v", err)
+	}
+	if router, ok := sm.shardHandler.(controller.ShardRouter); ok {
+		err = router.LoadTargets(ctx)
+		if err != nil {
+			logrus.Errorf("keeping current shard targets: %v", err)
+		}
+		router.RouteOperators(operators)
+	}
+
+	previousCache := sm.cache.ClusterCache
+	if !sm.guardRegistryChange(cache, resourceVersion, time.Now()) {
+		cache, resourceVersion = previousCache, sm.cache.ResourceVersion
 	}
 	sm.cache.ClusterCache = cache
+	sm.cache.ResourceVersion = resourceVersion
+	sm.overrides = sm.loadOverrides(ctx, sm.overrides)
+	sm.loadRevisions(ctx)
 	// Derive shard configurations from configurations
-	config, err := sm.deriveShardConfiguration()
+	now := time.Now()
+	assignment, reconciliation, err := sm.deriveShardConfiguration(operators, now)
 	if err != nil {
-		return fmt.Errorf("unable to derive shard configurations: %v", err)
+		return nil, nil, fmt.Errorf("unable to derive shard configurations: %v", err)
+	}
+	assignment, rollout := sm.limitRollout(ctx, assignment, reconciliation.triggers, operators, reconciliation.available, now)
+	pushed, migrations := sm.reconcileHandoffs(ctx, assignment, reconciliation.triggers, operators, now)
+	// nothing is audited, reported or handed off for changes which are not made
+	if !push {
+		return sm.previewShardConfiguration(ctx, pushed)
 	}
 	// Create/Update Shard CRD
-	err = sm.pushShardConfiguration(ctx, config)
+	err = sm.pushShardConfiguration(ctx, pushed)
 	if err != nil {
-		return fmt.Errorf("failed to push shard configuration: %v", err)
+		return nil, nil, fmt.Errorf("failed to push shard configuration: %v", err)
 	}
-	return nil
+	sm.recordRevision(ctx, assignment, resourceVersion, time.Now())
+	owners := getOwners(assignment)
+	crossOperatorDependencies.Set(int64(getCrossOperatorDependencies(assignment)))
+	sm.reportRebalance(sm.owners, owners)
+	sm.recordAudit(getAuditEntries(sm.owners, owners, reconciliation.triggers,
+		partitionClusters(append(previousCache, cache...), params), resourceVersion, time.Now()))
+	sm.owners = owners
+	sm.migrations = migrations
+	sm.rollout = rollout
+	reportRolloutProgress(rollout)
+	sm.failedOver = reconciliation.failedOver
+	sm.reportFailovers(reconciliation.records)
+	sm.reportRejectedOverrides(sm.overrideStatus.Rejected, reconciliation.overrideStatus.Rejected)
+	sm.overrideStatus = reconciliation.overrideStatus
+	sm.reportUnassignedClusters(sm.unassigned, reconciliation.unassigned)
+	sm.unassigned = reconciliation.unassigned
+	return nil, nil, nil
 }
 
 // loads configuration from registry for provide sharding manager identity
-func (sm *shardingManager) registryConfigSyncer(ctx context.Context) ([]registry.ClusterConfig, error) {
+// returns the configuration along with its registry resource version
+func (sm *shardingManager) registryConfigSyncer(ctx context.Context) ([]registry.ClusterConfig, string, error) {
 	var err error
 	var cache []registry.ClusterConfig
 	clusterConfiguration, err := sm.registryClient.GetClustersByShardingManagerIdentity(ctx, sm.identity)
 	if err != nil {
-		return cache, err
+		return cache, "", err
 	}
 	for _, cluster := range clusterConfiguration.Clusters {
 		identityConfig, err := sm.registryClient.GetIdentitiesByCluster(ctx, cluster.Name)
 		if err != nil {
-			return cache, err
+			return cache, "", err
 		}
 		cluster.IdentityConfig = identityConfig
 		cache = append(cache, cluster)
 	}
-	return cache, nil
+	return cache, clusterConfiguration.ResourceVersion, nil
+}
+
+// distributes parts of cached clusters amongst discovered operators after applying placement overrides
+// operators which failed are kept in the assignment with no clusters so that their shard is emptied
+func (sm *shardingManager) deriveShardConfiguration(operators []model.Operator, now time.Time) (model.ShardAssignment, healthReconciliation, error) {
+	placement := applyPlacementOverrides(partitionClusters(sm.cache.ClusterCache, sm.params), sm.overrides, getOperatorsHealth(operators, now, sm.params.OperatorGracePeriod))
+	reconciliation := sm.reconcileOperatorHealth(placement.clusters, operators, now)
+	reconciliation.overrideStatus = placement.status
+	for _, record := range reconciliation.records {
+		trigger := model.FailbackAuditTrigger
+		if record.reason == operatorFailoverReason {
+			trigger = model.FailoverAuditTrigger
+		}
+		for _, cluster := range record.clusters {
+			reconciliation.triggers[cluster] = trigger
+		}
+	}
+	for cluster, operator := range placement.pinned {
+		reconciliation.current[cluster] = operator
+		reconciliation.triggers[cluster] = model.OverrideAuditTrigger
+		delete(reconciliation.failedOver, cluster)
+	}
+	// a rollback moves clusters back to the operator handling them in the frozen revision
+	frozen := sm.getFrozenRevision()
+	for _, cluster := range applyRevision(frozen, placement.clusters, reconciliation.current, placement.pinned, reconciliation.available, sm.owners) {
+		reconciliation.triggers[cluster] = model.RollbackAuditTrigger
+	}
+	for _, cluster := range drainOperators(reconciliation.current, placement.pinned, operators, sm.params.DrainBatchSize) {
+		reconciliation.triggers[cluster] = model.DrainAuditTrigger
+	}
+	assignment, err := sm.loadDistributor.Distribute(placement.clusters, reconciliation.available, reconciliation.current)
+	if err != nil {
+		return nil, reconciliation, err
+	}
+	// rebalancing is frozen after a rollback until it is resumed
+	if sm.revisions.FrozenRevision == 0 {
+		var rebalanced []string
+		assignment, rebalanced = rebalanceAssignment(assignment, reconciliation.available, placement.pinned, sm.params)
+		if sm.params.DistributionStrategy == model.DependencyAwareStrategy {
+			var refined []string
+			assignment, refined = refineDependencyCut(assignment, reconciliation.available, placement.pinned, sm.params)
+			rebalanced = append(rebalanced, refined...)
+		}
+		for _, cluster := range rebalanced {
+			reconciliation.triggers[cluster] = model.RebalanceAuditTrigger
+		}
+	}
+	err = validateAssignment(assignment)
+	if err != nil {
+		return nil, reconciliation, err
+	}
+	for _, operator := range operators {
+		if _, ok := assignment[operator.Identity]; !ok {
+			assignment[operator.Identity] = []registry.ClusterConfig{}
+		}
+	}
+	reconciliation.unassigned = getUnassignedClusters(placement.clusters, assignment)
+	return assignment, reconciliation, nil
+}
+
+// computes the shards which would be pushed for the current registry configuration and discovered operators
+// through the same pipeline as a sync without modifying any shard, clusters stay with the operator handling
+// them in the live shards. Returns the desired shards along with the live shards they were derived from
+func (sm *shardingManager) DesiredShards(ctx context.Context) ([]*typeV1.Shard, []typeV1.Shard, error) {
+	shards, err := sm.shardHandler.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	sm.mutex.Lock()
+	sm.loadLiveAssignment(shards)
+	sm.mutex.Unlock()
+	return sm.sync(ctx, true)
+}
+
+// takes the live shards as the last applied assignment, so that clusters stay with the operator handling
+// them and registry configuration is guarded against the clusters they hold rather than being applied as
+// the first configuration
+func (sm *shardingManager) loadLiveAssignment(shards []typeV1.Shard) {
+	sm.owners = getOwnersFromShards(shards, sm.params)
+	sm.cache.ClusterCache = getClustersFromShards(shards, sm.params.OperatorIdentityLabel)
+}
+
+// operator handling each cluster according to the provided shards, clusters are keyed like the parts they
+// are split into by the configured partitioning
+func getOwnersFromShards(shards []typeV1.Shard, params *model.ShardingManagerParams) map[string]string {
+	owners := make(map[string]string)
+	for operatorIdentity, clusters := range getShardClusters(shards, params.OperatorIdentityLabel) {
+		for _, cluster := range partitionClusters(clusters, params) {
+			owners[getClusterKey(cluster)] = operatorIdentity
+		}
+	}
+	return owners
+}
+
+// clusters held by the provided shards sorted by name, identities of a cluster split into parts handled by
+// different operators are merged back into the cluster
+func getClustersFromShards(shards []typeV1.Shard, operatorIdentityLabel string) []registry.ClusterConfig {
+	var (
+		clusters []registry.ClusterConfig
+		indexes  = make(map[string]int)
+	)
+	for _, shardClusters := range getShardClusters(shards, operatorIdentityLabel) {
+		for _, cluster := range shardClusters {
+			index, ok := indexes[cluster.Name]
+			if !ok {
+				indexes[cluster.Name] = len(clusters)
+				clusters = append(clusters, cluster)
+				continue
+			}
+			clusters[index].IdentityConfig.AssetList = append(clusters[index].IdentityConfig.AssetList, cluster.IdentityConfig.AssetList...)
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+	return clusters
 }
 
-func (sm *shardingManager) deriveShardConfiguration() (model.ShardingMangerCache, error) {
-	return sm.cache, nil
+func getOwners(assignment model.ShardAssignment) map[string]string {
+	owners := make(map[string]string)
+	for operatorIdentity, clusters := range assignment {
+		for _, cluster := range clusters {
+			owners[getClusterKey(cluster)] = operatorIdentity
+		}
+	}
+	return owners
 }
@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/model"
+	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// registry serving a fixed cluster configuration
+type testRegistryClient struct {
+	clusters        []registry.ClusterConfig
+	resourceVersion string
+}
+
+func (rc *testRegistryClient) GetClustersByShardingManagerIdentity(ctx context.Context, shardingManagerIdentity string) (registry.ShardClusterConfig, error) {
+	return registry.ShardClusterConfig{Clusters: rc.clusters, ResourceVersion: rc.resourceVersion}, nil
+}
+
+func (rc *testRegistryClient) BulkSyncByShardingManagerIdentity(ctx context.Context, shardingManagerIdentity string) (registry.ShardClusterConfig, error) {
+	return rc.GetClustersByShardingManagerIdentity(ctx, shardingManagerIdentity)
+}
+
+func (rc *testRegistryClient) GetIdentitiesByCluster(ctx context.Context, clusterName string) (registry.IdentityConfig, error) {
+	for _, cluster := range rc.clusters {
+		if cluster.Name == clusterName {
+			return cluster.IdentityConfig, nil
+		}
+	}
+	return registry.IdentityConfig{ClusterName: clusterName}, nil
+}
+
+// discovers a fixed set of operators, listing is called whenever operators are listed when set
+type testOperatorHandler struct {
+	operators []model.Operator
+	listing   func()
+}
+
+func (oh *testOperatorHandler) List(ctx context.Context) ([]model.Operator, error) {
+	if oh.listing != nil {
+		oh.listing()
+	}
+	return oh.operators, nil
+}
+
+func (oh *testOperatorHandler) SetSchedulingState(ctx context.Context, operatorIdentity string, state string) error {
+	return nil
+}
+
+// counts the measurements made through a counter or a gauge
+type testMetric struct {
+	name         string
+	measurements int
+}
+
+func (m *testMetric) Increment(attributes api.MeasurementOption) {
+	m.measurements++
+}
+
+func (m *testMetric) Set(value int64, attributes ...attribute.KeyValue) {
+	m.measurements++
+}
+
+func (m *testMetric) Name() string {
+	return m.name
+}
@@ -5,4 +5,66 @@ const (
 	MetricsPort     = "9090"
 	MetricsPath     = "/metrics"
 	ShardNamePrefix = "shard"
+
+	// clusters moved away from a failed operator are moved back once it recovers
+	FailbackRecoveryPolicy = "failback"
+	// clusters moved away from a failed operator stay with the operator they failed over to
+	StayRecoveryPolicy = "stay"
+
+	// key of the overrides configmap which holds placement overrides
+	OverridesConfigMapKey = "overrides.yaml"
+
+	// prefix of the keys of the revision history configmap, every key holds one assignment revision
+	RevisionKeyPrefix = "revision-"
+	// annotation on the revision history configmap holding the revision the assignment is frozen at after a rollback
+	FrozenRevisionAnnotation = "admiral.io/frozenRevision"
+
+	// annotation on operator lease which marks the operator as cordoned or draining
+	OperatorSchedulingStateAnnotation = "admiral.io/operatorSchedulingState"
+	// no new clusters are assigned to a cordoned operator, it keeps the clusters it already handles
+	CordonedSchedulingState = "cordoned"
+	// no new clusters are assigned to a draining operator and its clusters are gradually moved away
+	DrainingSchedulingState = "draining"
+
+	// clusters are assigned to the operator with least load relative to its capacity
+	LeastLoadedStrategy = "least-loaded"
+	// clusters are assigned to the least loaded operator in the same locality, other operators are
+	// only used when no operator runs in the cluster's locality
+	LocalityAwareStrategy = "locality-aware"
+	// clusters are partitioned by a metadata key and each partition is assigned to the least loaded operator
+	// of the pool labelled with the same segment, operators of other segments are never used
+	SegmentedStrategy = "segmented"
+	// clusters hosting the source and destination of the same identity are kept on the same operator as long
+	// as it does not become overloaded
+	DependencyAwareStrategy = "dependency-aware"
+
+	// every cluster is handled by a single operator
+	ClusterShardingGranularity = "cluster"
+	// every identity of a cluster is distributed on its own, so identities of a cluster can be handled by
+	// different operators
+	IdentityShardingGranularity = "identity"
+
+	// capacity of operators which do not declare one
+	DefaultOperatorCapacity = 1.0
+
+	// shard target of the cluster sharding manager runs in
+	LocalShardTarget = "local"
+
+	// shard operations skipped in dry run mode
+	CreateShardOperation = "create"
+	UpdateShardOperation = "update"
+	DeleteShardOperation = "delete"
+
+	// triggers of assignment changes recorded in the audit log. Registry changes are picked up by the periodic
+	// bulk sync, there is no event driven sync, so they are recorded with the bulk sync trigger
+	BulkSyncAuditTrigger  = "bulk-sync"
+	FailoverAuditTrigger  = "failover"
+	FailbackAuditTrigger  = "failback"
+	OverrideAuditTrigger  = "override"
+	DrainAuditTrigger     = "drain"
+	RolloutAuditTrigger   = "rollout"
+	RebalanceAuditTrigger = "rebalance"
+	RollbackAuditTrigger  = "rollback"
+	// audit log is written to standard output instead of a file
+	StdoutAuditLogPath = "-"
 )
@@ -1,8 +1,12 @@
 package model
 
 import (
+	"time"
+
 	admiralv1 "github.com/istio-ecosystem/admiral-api/pkg/client/clientset/versioned/typed/admiral/v1"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/registry"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 type ShardingManagerParams struct {
@@ -11,7 +15,67 @@ type ShardingManagerParams struct {
 	ShardIdentityLabel      string
 	ShardNamespace          string
 	KubeconfigPath          string
+	KubeconfigContext       string
+	ImpersonateUser         string
+	ImpersonateGroups       []string
+	KubeClientQPS           float32
+	KubeClientBurst         int
 	RegistryEndpoint        string
+	OperatorGracePeriod     time.Duration
+	FailoverRecoveryPolicy  string
+	OperatorCapacityKey     string
+	MaxClustersPerShard     int
+	MaxIdentitiesPerShard   int
+	MaxShardSizeBytes       int
+	OverridesConfigMap      string
+	// configmap in the shard namespace keeping the last revisions of the assignment, no history is kept when
+	// the limit is not positive
+	RevisionHistoryConfigMap string
+	RevisionHistoryLimit     int
+	DrainBatchSize           int
+	DistributionStrategy     string
+	OperatorLocalityLabel    string
+	// cluster metadata key and operator label partitioning clusters and operators into segments
+	SegmentKey           string
+	OperatorSegmentLabel string
+	// operator pool handling identities of each environment, operators declare their pool using a label
+	EnvironmentPools  map[string]string
+	OperatorPoolLabel string
+	// unit of distribution, either whole clusters or single identities of a cluster
+	ShardingGranularity string
+	// time a cluster is kept by its previous operator until its new operator acknowledged it, clusters are
+	// moved at once when not positive
+	HandoffTimeout time.Duration
+	// registry configuration removing more clusters than either limit is rejected, limits are not applied when zero
+	MaxRegistryRemovalPercent float64
+	MaxRegistryRemovals       int
+	// clusters moved between operators which can still handle them are moved in waves of at most this many
+	// clusters every rollout interval, moves are not limited when not positive
+	RolloutMaxMoves int
+	RolloutInterval time.Duration
+	// semicolon separated cron windows, e.g. "0 2 * * 1-5 3h", evaluated in UTC outside of which only mandatory
+	// changes are applied and optional moves are queued. Moves are not restricted to windows when empty
+	RebalanceWindows string
+	// clusters are moved between operators once the difference between the highest and lowest weighted load,
+	// relative to the average weighted load, exceeds the threshold, until it is back below the threshold minus
+	// the hysteresis. The assignment is kept as is when the threshold is not positive
+	RebalanceThreshold  float64
+	RebalanceHysteresis float64
+	OutputDir           string
+	// operators are read from this file instead of their heartbeat lease when shards are rendered to a directory
+	OperatorsFile string
+	// shards are neither created, updated nor deleted, the changes which would be made are reported instead
+	DryRun     bool
+	SyncPeriod time.Duration
+	// kubeconfig path of each additional cluster shards are published to, keyed by target name
+	ShardTargets              map[string]string
+	ShardTargetSecretSelector string
+	OperatorTargetLabel       string
+	AuditLogPath              string
+	AuditLogSize              int
+	// file holding the bearer token required by the admin endpoints which change the assignment, these
+	// endpoints are disabled when empty
+	AdminTokenFile string
 }
 
 type ShardingManagerConfig struct {
@@ -19,10 +83,213 @@ type ShardingManagerConfig struct {
 }
 
 type Clients struct {
-	AdmiralClient  admiralv1.AdmiralV1Interface
-	RegistryClient registry.RegistryConfigInterface
+	AdmiralClient    admiralv1.AdmiralV1Interface
+	KubernetesClient kubernetes.Interface
+	RegistryClient   registry.RegistryConfigInterface
+	EventRecorder    record.EventRecorder
 }
 
 type ShardingMangerCache struct {
 	ClusterCache []registry.ClusterConfig
+	// resource version of the registry configuration the cache was loaded from
+	ResourceVersion string
+}
+
+// admiral operator discovered through its heartbeat lease
+type Operator struct {
+	Identity      string
+	Namespace     string
+	LeaseName     string
+	Labels        map[string]string
+	Annotations   map[string]string
+	LastHeartbeat time.Time
+	LeaseDuration time.Duration
+	// relative amount of load the operator can handle, operators with higher capacity receive proportionally more load
+	Capacity float64
+	// set when operator is cordoned or draining
+	SchedulingState string
+	// locality the operator runs in
+	Locality string
+	// segment whose clusters the operator handles when clusters are partitioned into segments
+	Segment string
+	// environment pool whose identities the operator handles when identities are partitioned by environment
+	Pool string
+	// shard target the operator runs in, shards of operators without a target are published locally
+	Target string
+}
+
+// clusters assigned to each operator, keyed by operator identity
+type ShardAssignment map[string][]registry.ClusterConfig
+
+// pins a cluster or identity to an operator, or excludes it from sharding
+type PlacementOverride struct {
+	Cluster  string `json:"cluster,omitempty"`
+	Identity string `json:"identity,omitempty"`
+	// label selector matched against cluster metadata, e.g. "segment=payments,tier!=bronze"
+	ClusterSelector string `json:"clusterSelector,omitempty"`
+	Operator        string `json:"operator,omitempty"`
+	Exclude         bool   `json:"exclude,omitempty"`
+}
+
+type PlacementOverrides struct {
+	Overrides []PlacementOverride `json:"overrides,omitempty"`
+}
+
+// placement override which could not be applied and why
+type RejectedOverride struct {
+	Override PlacementOverride `json:"override"`
+	Reason   string            `json:"reason"`
+}
+
+type OverrideStatus struct {
+	Applied  []PlacementOverride `json:"applied"`
+	Rejected []RejectedOverride  `json:"rejected"`
+}
+
+// load handled by an operator in a distribution plan
+type OperatorLoad struct {
+	Operator      string   `json:"operator"`
+	Locality      string   `json:"locality,omitempty"`
+	Segment       string   `json:"segment,omitempty"`
+	Pool          string   `json:"pool,omitempty"`
+	Capacity      float64  `json:"capacity"`
+	Clusters      []string `json:"clusters"`
+	Identities    int      `json:"identities"`
+	Load          int      `json:"load"`
+	WeightedLoad  float64  `json:"weightedLoad"`
+	CrossLocality int      `json:"crossLocality"`
+}
+
+// balance statistics of a distribution plan, computed on load relative to operator capacity
+type DistributionStatistics struct {
+	MaxLoad       float64 `json:"maxLoad"`
+	MinLoad       float64 `json:"minLoad"`
+	StdDevLoad    float64 `json:"stdDevLoad"`
+	CrossLocality int     `json:"crossLocality"`
+	// weight of dependencies between clusters handled by different operators
+	CrossOperatorDependencies int `json:"crossOperatorDependencies"`
+}
+
+// assignment produced by a distribution strategy along with its balance statistics
+type DistributionPlan struct {
+	Strategy   string                 `json:"strategy"`
+	Operators  []OperatorLoad         `json:"operators"`
+	Statistics DistributionStatistics `json:"statistics"`
+}
+
+// difference between the desired and live state of a shard
+// identities are represented as <cluster>/<identity>
+type ShardDiff struct {
+	Shard             string   `json:"shard"`
+	Operator          string   `json:"operator,omitempty"`
+	AddedClusters     []string `json:"addedClusters,omitempty"`
+	RemovedClusters   []string `json:"removedClusters,omitempty"`
+	AddedIdentities   []string `json:"addedIdentities,omitempty"`
+	RemovedIdentities []string `json:"removedIdentities,omitempty"`
+}
+
+// shard change which would have been made by the last sync in dry run mode
+type DryRunChange struct {
+	Operation string `json:"operation"`
+	ShardDiff
+}
+
+// changes which would have been made by the last sync in dry run mode
+type DryRunStatus struct {
+	Enabled                 bool           `json:"enabled"`
+	Time                    time.Time      `json:"time,omitempty"`
+	RegistryResourceVersion string         `json:"registryResourceVersion,omitempty"`
+	Changes                 []DryRunChange `json:"changes"`
+}
+
+// health of a cluster shards are published to
+type ShardTargetStatus struct {
+	Name                string    `json:"name"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastError           string    `json:"lastError,omitempty"`
+	LastSuccess         time.Time `json:"lastSuccess,omitempty"`
+}
+
+// change of the operator handling a cluster
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Cluster    string    `json:"cluster"`
+	Identities []string  `json:"identities,omitempty"`
+	// empty when the cluster was not assigned before
+	PreviousOperator string `json:"previousOperator,omitempty"`
+	// empty when the cluster is no longer assigned
+	Operator                string `json:"operator,omitempty"`
+	Trigger                 string `json:"trigger"`
+	RegistryResourceVersion string `json:"registryResourceVersion,omitempty"`
+}
+
+// cluster handed off between operators, its previous operator keeps the cluster until the new operator
+// acknowledged it or the handoff timed out
+type Migration struct {
+	Cluster  string    `json:"cluster"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Started  time.Time `json:"started"`
+	Deadline time.Time `json:"deadline"`
+	// last sync the new operator reported when the handoff started, on the clock of the operator. Nil until
+	// the shards of the new operator could be listed
+	LastSynced *time.Time `json:"lastSynced,omitempty"`
+}
+
+// registry configuration rejected for removing too many clusters
+type RegistryChange struct {
+	Time             time.Time `json:"time"`
+	ResourceVersion  string    `json:"resourceVersion,omitempty"`
+	PreviousClusters int       `json:"previousClusters"`
+	Clusters         int       `json:"clusters"`
+	Removed          []string  `json:"removed"`
+}
+
+type RegistryGuardStatus struct {
+	// nil when the last registry configuration was applied
+	Rejected *RegistryChange `json:"rejected,omitempty"`
+	// clusters whose removal was accepted through the admin api
+	Accepted []string `json:"accepted"`
+}
+
+// cluster move queued for a later rollout wave
+type RolloutMove struct {
+	Cluster string `json:"cluster"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// progress of the current or last rollout of cluster moves
+type RolloutStatus struct {
+	// optional moves are queued outside of rebalance windows until the next window opens
+	OutsideWindow bool      `json:"outsideWindow"`
+	NextWindow    time.Time `json:"nextWindow,omitempty"`
+	Started       time.Time `json:"started,omitempty"`
+	// clusters moved since the rollout started
+	Moved   int           `json:"moved"`
+	Pending []RolloutMove `json:"pending"`
+	// start of the current wave and clusters moved during it
+	WaveStarted time.Time `json:"waveStarted,omitempty"`
+	WaveMoves   int       `json:"waveMoves"`
+	// no moves are applied while operators report errors on their shards
+	Paused       bool   `json:"paused"`
+	PausedReason string `json:"pausedReason,omitempty"`
+	// no optional moves are applied while the assignment is frozen at a revision after a rollback
+	FrozenRevision int `json:"frozenRevision,omitempty"`
+}
+
+// assignment pushed by a sync, clusters of every operator are identified like the parts they are split into
+type AssignmentRevision struct {
+	Revision                int                 `json:"revision"`
+	Time                    time.Time           `json:"time"`
+	RegistryResourceVersion string              `json:"registryResourceVersion,omitempty"`
+	Operators               map[string][]string `json:"operators"`
+}
+
+// revisions kept in the revision history, oldest first
+type RevisionHistory struct {
+	Revisions []AssignmentRevision `json:"revisions"`
+	// revision the assignment is frozen at after a rollback, 0 when rebalancing is not frozen
+	FrozenRevision int `json:"frozenRevision,omitempty"`
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"log"
 	"reflect"
+	"sync"
 
+	"go.opentelemetry.io/otel/attribute"
 	api "go.opentelemetry.io/otel/metric"
 )
 
@@ -59,3 +61,69 @@ func newFloat64Counter(name, description string, opts *options) *counter {
 		int64Counter: int64Counter,
 	}
 }
+
+// Gauge reports the last value set for every set of attributes
+type Gauge interface {
+	Set(value int64, attributes ...attribute.KeyValue)
+	Name() string
+}
+
+// NewGauge returns a new gauge
+func NewGauge(name, description string, opts ...Options) Gauge {
+	o := createOptions(opts...)
+	return newInt64Gauge(name, description, o)
+}
+
+type gauge struct {
+	name        string
+	description string
+	mutex       sync.Mutex
+	values      map[attribute.Distinct]gaugeValue
+}
+
+type gaugeValue struct {
+	attributes attribute.Set
+	value      int64
+}
+
+// Set records the value of the gauge for the provided attributes
+func (g *gauge) Set(value int64, attributes ...attribute.KeyValue) {
+	set := attribute.NewSet(attributes...)
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.values[set.Equivalent()] = gaugeValue{attributes: set, value: value}
+}
+
+// Name returns the name of the metric
+func (g *gauge) Name() string {
+	return g.name
+}
+
+func newInt64Gauge(name, description string, opts *options) *gauge {
+	meter := defaultMeter
+	if reflect.ValueOf(opts.meter).IsValid() {
+		meter = opts.meter
+	}
+	g := &gauge{
+		name:        name,
+		description: description,
+		values:      make(map[attribute.Distinct]gaugeValue),
+	}
+	_, err := meter.Int64ObservableGauge(
+		name,
+		api.WithUnit("1"),
+		api.WithDescription(description),
+		api.WithInt64Callback(func(_ context.Context, observer api.Int64Observer) error {
+			g.mutex.Lock()
+			defer g.mutex.Unlock()
+			for _, value := range g.values {
+				observer.Observe(value.value, api.WithAttributeSet(value.attributes))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		log.Fatalf("error creating int64 gauge: %v", err)
+	}
+	return g
+}
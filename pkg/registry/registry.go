@@ -38,11 +38,88 @@ type ShardClusterConfig struct {
 type ClusterConfig struct {
 	Name           string          `json:"name,omitempty"`
 	Locality       string          `json:"locality,omitempty"`
-	Metadata       clusterMetadata `json:"metadata,omitempty"`
+	Metadata       ClusterMetadata `json:"metadata,omitempty"`
 	IdentityConfig IdentityConfig  `json:"assets,omitempty"`
+	// set by sharding manager when identities of the cluster are split into parts handled by different
+	// operators, it is not part of the registry configuration
+	Partition string `json:"-"`
 }
 
-type clusterMetadata struct {
+// metadata of a cluster, well known fields are typed and any other field is kept in Attributes
+type ClusterMetadata struct {
+	Segment        string `json:"segment,omitempty"`
+	Tier           string `json:"tier,omitempty"`
+	ComplianceZone string `json:"complianceZone,omitempty"`
+	// fields which are not well known, keyed by their name in registry
+	Attributes map[string]string `json:"-"`
+}
+
+// names of well known metadata fields in registry
+const (
+	segmentMetadataKey        = "segment"
+	tierMetadataKey           = "tier"
+	complianceZoneMetadataKey = "complianceZone"
+)
+
+func (m *ClusterMetadata) UnmarshalJSON(data []byte) error {
+	var fields map[string]any
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	*m = ClusterMetadata{}
+	for key, field := range fields {
+		value, ok := field.(string)
+		if !ok {
+			// values which are not strings are kept in their json representation
+			encoded, err := json.Marshal(field)
+			if err != nil {
+				return err
+			}
+			value = string(encoded)
+		}
+		switch key {
+		case segmentMetadataKey:
+			m.Segment = value
+		case tierMetadataKey:
+			m.Tier = value
+		case complianceZoneMetadataKey:
+			m.ComplianceZone = value
+		default:
+			if m.Attributes == nil {
+				m.Attributes = make(map[string]string)
+			}
+			m.Attributes[key] = value
+		}
+	}
+	return nil
+}
+
+func (m ClusterMetadata) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Labels())
+}
+
+// every metadata field keyed by its name in registry, used to select clusters
+func (m ClusterMetadata) Labels() map[string]string {
+	labels := make(map[string]string)
+	for key, value := range m.Attributes {
+		labels[key] = value
+	}
+	for key, value := range map[string]string{
+		segmentMetadataKey:        m.Segment,
+		tierMetadataKey:           m.Tier,
+		complianceZoneMetadataKey: m.ComplianceZone,
+	} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// value of a metadata field by its name in registry, empty when the field is not set
+func (m ClusterMetadata) Get(key string) string {
+	return m.Labels()[key]
 }
 
 // mesh workload identity configuration for cluster
@@ -13,12 +13,12 @@ func getExpectedClusterConfiguration() ShardClusterConfig {
 	cluster1 := ClusterConfig{
 		Name:     "cluster1",
 		Locality: "us-west-2",
-		Metadata: clusterMetadata{},
+		Metadata: ClusterMetadata{Segment: "seg"},
 	}
 	cluster2 := ClusterConfig{
 		Name:     "cluster2",
 		Locality: "us-east-2",
-		Metadata: clusterMetadata{},
+		Metadata: ClusterMetadata{Segment: "seg"},
 	}
 
 	var clusterConfigs = []ClusterConfig{}
@@ -39,7 +39,7 @@ func getExpectedBulkClusterConfiguration() ShardClusterConfig {
 	cluster1 := ClusterConfig{
 		Name:     "cluster1",
 		Locality: "us-west-2",
-		Metadata: clusterMetadata{},
+		Metadata: ClusterMetadata{Segment: "seg"},
 		IdentityConfig: IdentityConfig{
 			ClusterName: "cluster1",
 			AssetList: []AssetList{{
@@ -53,7 +53,7 @@ func getExpectedBulkClusterConfiguration() ShardClusterConfig {
 	cluster2 := ClusterConfig{
 		Name:     "cluster2",
 		Locality: "us-east-2",
-		Metadata: clusterMetadata{},
+		Metadata: ClusterMetadata{Segment: "seg"},
 	}
 
 	var clusterConfigs = []ClusterConfig{}
@@ -357,3 +357,47 @@ func TestGetIdentitiesByCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestParsingClusterMetadata(t *testing.T) {
+	testCases := []struct {
+		name             string
+		metadata         string
+		expectedMetadata ClusterMetadata
+		expectedLabels   map[string]string
+	}{
+		{
+			name: "Given JSON cluster metadata with well known fields only, " +
+				"When it is unmarshalled, " +
+				"Then fields should be read into typed fields",
+			metadata:         `{"segment": "seg", "tier": "gold", "complianceZone": "pci"}`,
+			expectedMetadata: ClusterMetadata{Segment: "seg", Tier: "gold", ComplianceZone: "pci"},
+			expectedLabels:   map[string]string{"segment": "seg", "tier": "gold", "complianceZone": "pci"},
+		},
+		{
+			name: "Given JSON cluster metadata with fields which are not well known, " +
+				"When it is unmarshalled, " +
+				"Then those fields should be kept as attributes",
+			metadata: `{"segment": "seg", "team": "payments", "replicas": 3}`,
+			expectedMetadata: ClusterMetadata{
+				Segment:    "seg",
+				Attributes: map[string]string{"team": "payments", "replicas": "3"},
+			},
+			expectedLabels: map[string]string{"segment": "seg", "team": "payments", "replicas": "3"},
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var metadata ClusterMetadata
+			err := json.Unmarshal([]byte(c.metadata), &metadata)
+			if err != nil {
+				t.Fatalf("while unmarshaling JSON into ClusterMetadata struct, got error: %s", err)
+			}
+			if !cmp.Equal(metadata, c.expectedMetadata) {
+				t.Errorf(cmp.Diff(metadata, c.expectedMetadata))
+			}
+			if !cmp.Equal(metadata.Labels(), c.expectedLabels) {
+				t.Errorf(cmp.Diff(metadata.Labels(), c.expectedLabels))
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longest window, bounds the number of start times checked to tell whether a time is within a window
+const MaxWindowDuration = 7 * 24 * time.Hour
+
+// window opening at every time matching a cron expression and staying open for a duration, written as
+// the five cron fields followed by the duration, e.g. "0 2 * * 1-5 3h" opens at 02:00 on weekdays for
+// three hours. Fields support *, values, ranges, lists and steps, days of week go from 0 (Sunday) to 7
+type Window struct {
+	spec     string
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	anyDom   bool
+	anyDow   bool
+	duration time.Duration
+}
+
+func ParseWindow(spec string) (Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 6 {
+		return Window{}, fmt.Errorf("window %q must consist of five cron fields and a duration", spec)
+	}
+	window := Window{
+		spec:   strings.Join(fields, " "),
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}
+	var err error
+	for _, field := range []struct {
+		name  string
+		value string
+		min   int
+		max   int
+		bits  *uint64
+	}{
+		{"minute", fields[0], 0, 59, &window.minute},
+		{"hour", fields[1], 0, 23, &window.hour},
+		{"day of month", fields[2], 1, 31, &window.dom},
+		{"month", fields[3], 1, 12, &window.month},
+		{"day of week", fields[4], 0, 7, &window.dow},
+	} {
+		*field.bits, err = parseField(field.value, field.min, field.max)
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid %s of window %q: %v", field.name, spec, err)
+		}
+	}
+	// sunday can be written as 0 or 7
+	if window.dow&(1<<7) != 0 {
+		window.dow |= 1
+	}
+	window.duration, err = time.ParseDuration(fields[5])
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid duration of window %q: %v", spec, err)
+	}
+	if window.duration <= 0 || window.duration > MaxWindowDuration {
+		return Window{}, fmt.Errorf("duration of window %q must be positive and at most %s", spec, MaxWindowDuration)
+	}
+	return window, nil
+}
+
+// parses windows separated by semicolons, as commas are part of cron fields
+func ParseWindows(specs string) ([]Window, error) {
+	var windows []Window
+	for _, spec := range strings.Split(specs, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		window, err := ParseWindow(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// parses a comma separated list of values, ranges and steps into a bit set of the allowed values
+func parseField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("step %q must be a positive number", stepPart)
+			}
+		}
+		start, end := min, max
+		if rangePart != "*" {
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			start, err = strconv.Atoi(low)
+			if err != nil {
+				return 0, fmt.Errorf("value %q must be a number", low)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(high)
+				if err != nil {
+					return 0, fmt.Errorf("value %q must be a number", high)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is not within %d-%d", rangePart, min, max)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// whether a window opens at the provided minute, days match when either the day of month or the day of
+// week matches unless one of them is *
+func (w Window) opensAt(t time.Time) bool {
+	if w.minute&(1<<t.Minute()) == 0 || w.hour&(1<<t.Hour()) == 0 || w.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	return w.matchesDay(t)
+}
+
+func (w Window) matchesDay(t time.Time) bool {
+	dom := w.dom&(1<<t.Day()) != 0
+	dow := w.dow&(1<<int(t.Weekday())) != 0
+	if w.anyDom || w.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// whether the provided time falls within an occurrence of the window
+func (w Window) Contains(t time.Time) bool {
+	for start := t.Truncate(time.Minute); t.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.opensAt(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// first time the window opens after the provided time, false when it does not open within a year
+func (w Window) Next(t time.Time) (time.Time, bool) {
+	start := t.Truncate(time.Minute).Add(time.Minute)
+	limit := start.AddDate(1, 0, 0)
+	for start.Before(limit) {
+		switch {
+		case w.month&(1<<int(start.Month())) == 0 || !w.matchesDay(start):
+			start = time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+		case w.hour&(1<<start.Hour()) == 0:
+			start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour()+1, 0, 0, 0, start.Location())
+		case w.minute&(1<<start.Minute()) == 0:
+			start = start.Add(time.Minute)
+		default:
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (w Window) String() string {
+	return w.spec
+}
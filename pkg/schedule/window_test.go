@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// a friday
+var testNow = time.Date(2024, 6, 21, 18, 25, 0, 0, time.UTC)
+
+func TestParseWindow(t *testing.T) {
+	testCases := []struct {
+		name          string
+		spec          string
+		expectedError string
+	}{
+		{
+			name: "Given a window with lists, ranges and steps, " +
+				"When the window is parsed, " +
+				"Then there should be no error",
+			spec: "*/15 1,2,20-23 * 1-12/2 1-5 90m",
+		},
+		{
+			name: "Given a window without duration, " +
+				"When the window is parsed, " +
+				"Then an error should be returned",
+			spec:          "0 2 * * *",
+			expectedError: "window \"0 2 * * *\" must consist of five cron fields and a duration",
+		},
+		{
+			name: "Given a window with a value out of range, " +
+				"When the window is parsed, " +
+				"Then an error should be returned",
+			spec:          "0 24 * * * 1h",
+			expectedError: "invalid hour of window \"0 24 * * * 1h\": \"24\" is not within 0-23",
+		},
+		{
+			name: "Given a window longer than a week, " +
+				"When the window is parsed, " +
+				"Then an error should be returned",
+			spec:          "0 0 * * 0 200h",
+			expectedError: "duration of window \"0 0 * * 0 200h\" must be positive and at most 168h0m0s",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var actualError string
+			_, err := ParseWindow(c.spec)
+			if err != nil {
+				actualError = err.Error()
+			}
+			if actualError != c.expectedError {
+				t.Errorf(cmp.Diff(actualError, c.expectedError))
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	testCases := []struct {
+		name             string
+		spec             string
+		expectedContains bool
+		expectedNext     time.Time
+	}{
+		{
+			name: "Given a daily window which opened before, " +
+				"When it is checked, " +
+				"Then it should contain the time",
+			spec:             "0 18 * * * 1h",
+			expectedContains: true,
+			expectedNext:     time.Date(2024, 6, 22, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Given a daily window which closed before, " +
+				"When it is checked, " +
+				"Then it should not contain the time",
+			spec:         "0 17 * * * 1h",
+			expectedNext: time.Date(2024, 6, 22, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Given a weekend window, " +
+				"When it is checked on a friday, " +
+				"Then it should open on saturday",
+			spec:         "0 0 * * 6 48h",
+			expectedNext: time.Date(2024, 6, 22, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Given a window opening on sunday written as 7, " +
+				"When it is checked on a friday, " +
+				"Then it should open on sunday",
+			spec:         "30 22 * * 7 2h",
+			expectedNext: time.Date(2024, 6, 23, 22, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "Given a window restricting both day of month and day of week, " +
+				"When it is checked, " +
+				"Then it should open on either day",
+			spec:             "0 18 1 * 5 1h",
+			expectedContains: true,
+			expectedNext:     time.Date(2024, 6, 28, 18, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			window, err := ParseWindow(c.spec)
+			if err != nil {
+				t.Fatalf("failed to parse window: %v", err)
+			}
+			if window.Contains(testNow) != c.expectedContains {
+				t.Errorf("expected contains to be %v", c.expectedContains)
+			}
+			next, _ := window.Next(testNow)
+			if !next.Equal(c.expectedNext) {
+				t.Errorf("expected next window at %s, got %s", c.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("0 2 * * 1-5 3h; 0 0 * * 0,6 24h;")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var actual []string
+	for _, window := range windows {
+		actual = append(actual, window.String())
+	}
+	expected := []string{"0 2 * * 1-5 3h", "0 0 * * 0,6 24h"}
+	if !cmp.Equal(actual, expected) {
+		t.Errorf(cmp.Diff(actual, expected))
+	}
+}
@@ -2,9 +2,14 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/controller"
 	"github.com/istio-ecosystem/admiral-sharding-manager/pkg/manager"
@@ -16,8 +21,24 @@ import (
 )
 
 const (
-	livenessPath  = "/liveness"
-	readinessPath = "/readiness"
+	livenessPath                 = "/liveness"
+	readinessPath                = "/readiness"
+	adminOverridesPath           = "/admin/overrides"
+	adminTargetsPath             = "/admin/targets"
+	adminAuditPath               = "/admin/audit"
+	adminMigrationsPath          = "/admin/migrations"
+	adminRegistryGuardPath       = "/admin/registry-guard"
+	adminRegistryGuardAcceptPath = "/admin/registry-guard/accept"
+	adminRolloutPath             = "/admin/rollout"
+	adminRevisionsPath           = "/admin/revisions"
+	adminRollbackPath            = "/admin/rollback"
+	adminResumePath              = "/admin/resume"
+	adminDryRunPath              = "/admin/dry-run"
+
+	// number of audit entries returned when no limit is requested
+	defaultAuditLimit = 100
+
+	eventComponent = "admiral-sharding-manager"
 )
 
 var (
@@ -29,8 +50,13 @@ var (
 )
 
 type server struct {
-	mux     *http.ServeMux
-	options *options
+	mux             *http.ServeMux
+	options         *options
+	shardingManager manager.AdminInterface
+	// set when shards are published to multiple clusters
+	shardRouter controller.ShardRouter
+	// file holding the token required by the admin endpoints which change the assignment
+	adminTokenFile string
 }
 
 type options struct {
@@ -53,8 +79,12 @@ func NewServer(ctx context.Context, params *model.ShardingManagerParams, opts ..
 	if err != nil {
 		return nil, fmt.Errorf("failed setting up clients: %v", err)
 	}
-	shardHandler := controller.NewShardHandler(client, params)
-	shardingManager, err := manager.NewShardingManager(ctx, shardHandler, client, params.ShardingManagerIdentity)
+	shardHandler, shardRouter, err := initShardHandler(ctx, client, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed setting up shard targets: %v", err)
+	}
+	operatorHandler, overrideHandler, revisionHandler := initHandlers(client, params)
+	shardingManager, err := manager.NewShardingManager(ctx, shardHandler, operatorHandler, overrideHandler, revisionHandler, client, params)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing sharding manager: %v", err)
 	}
@@ -64,30 +94,97 @@ func NewServer(ctx context.Context, params *model.ShardingManagerParams, opts ..
 	}
 
 	httpServer := &server{
-		options: createOptions(opts...),
-		mux:     http.NewServeMux(),
+		options:         createOptions(opts...),
+		mux:             http.NewServeMux(),
+		shardingManager: shardingManager,
+		shardRouter:     shardRouter,
+		adminTokenFile:  params.AdminTokenFile,
 	}
 	httpServer.mux.HandleFunc(livenessPath, httpServer.livenessHandler)
 	httpServer.mux.HandleFunc(readinessPath, httpServer.readinessHandler)
+	httpServer.mux.HandleFunc(adminOverridesPath, httpServer.overridesHandler)
+	httpServer.mux.HandleFunc(adminTargetsPath, httpServer.targetsHandler)
+	httpServer.mux.HandleFunc(adminAuditPath, httpServer.auditHandler)
+	httpServer.mux.HandleFunc(adminMigrationsPath, httpServer.migrationsHandler)
+	httpServer.mux.HandleFunc(adminRegistryGuardPath, httpServer.registryGuardHandler)
+	httpServer.mux.HandleFunc(adminRegistryGuardAcceptPath, httpServer.authorize(adminRegistryGuardAcceptPath, httpServer.registryGuardAcceptHandler))
+	httpServer.mux.HandleFunc(adminRolloutPath, httpServer.rolloutHandler)
+	httpServer.mux.HandleFunc(adminRevisionsPath, httpServer.revisionsHandler)
+	httpServer.mux.HandleFunc(adminRollbackPath, httpServer.authorize(adminRollbackPath, httpServer.rollbackHandler))
+	httpServer.mux.HandleFunc(adminResumePath, httpServer.authorize(adminResumePath, httpServer.resumeHandler))
+	httpServer.mux.HandleFunc(adminDryRunPath, httpServer.dryRunHandler)
 	return httpServer, nil
 }
 
 func initClients(params *model.ShardingManagerParams) (model.Clients, error) {
 	var client model.Clients
-	var kubeClient manager.LoadKubeClient = &manager.KubeClient{}
-	admiralAPIClient, err := kubeClient.LoadAdmiralApiClientFromPath(params.KubeconfigPath)
+	// sharding manager does not access the cluster when shards are rendered to a directory
+	if params.OutputDir != "" {
+		client.RegistryClient = registry.NewRegistryClient(registry.WithEndpoint(params.RegistryEndpoint))
+		return client, nil
+	}
+	admiralAPIClient, kubernetesClient, err := manager.LoadKubeClients(&manager.KubeClient{}, params)
 	if err != nil {
-		return client, fmt.Errorf("failed to initialize admiral api client")
+		return client, err
 	}
 	client.AdmiralClient = admiralAPIClient
+	client.KubernetesClient = kubernetesClient
+	client.EventRecorder = manager.NewEventRecorder(kubernetesClient, eventComponent)
 	client.RegistryClient = registry.NewRegistryClient(registry.WithEndpoint(params.RegistryEndpoint))
 	return client, nil
 }
 
+// operators are read from the operators file when shards are rendered to a directory, placement overrides
+// and the revision history are only available when sharding manager accesses the cluster
+func initHandlers(client model.Clients, params *model.ShardingManagerParams) (controller.OperatorInterface, controller.OverrideInterface, controller.RevisionInterface) {
+	if params.OutputDir != "" {
+		return controller.NewFileOperatorHandler(params), nil, nil
+	}
+	return controller.NewOperatorHandler(client, params), controller.NewOverrideHandler(client, params), controller.NewRevisionHandler(client, params)
+}
+
+// shards are rendered to the output directory when one is configured, and published to the cluster each
+// operator runs in when shard targets are configured
+func initShardHandler(ctx context.Context, client model.Clients, params *model.ShardingManagerParams) (controller.ShardInterface, controller.ShardRouter, error) {
+	if params.OutputDir != "" {
+		return controller.NewFileShardHandler(params), nil, nil
+	}
+	shardHandler := controller.NewShardHandler(client, params)
+	if len(params.ShardTargets) == 0 && params.ShardTargetSecretSelector == "" {
+		return shardHandler, nil, nil
+	}
+	// shard targets are resolved again on every sync so that shard target secrets can be added or removed
+	loader := func(ctx context.Context, params *model.ShardingManagerParams) (map[string]controller.ShardInterface, error) {
+		targetClients, err := manager.LoadShardTargets(ctx, &manager.KubeClient{}, client.KubernetesClient, params)
+		if err != nil {
+			return nil, err
+		}
+		targets := make(map[string]controller.ShardInterface)
+		for name, admiralClient := range targetClients {
+			targetClient := client
+			targetClient.AdmiralClient = admiralClient
+			targets[name] = controller.NewShardHandler(targetClient, params)
+		}
+		return targets, nil
+	}
+	targets, err := loader(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	targets[model.LocalShardTarget] = shardHandler
+	multiClusterShardHandler := controller.NewMultiClusterShardHandler(targets, loader, params)
+	return multiClusterShardHandler, multiClusterShardHandler, nil
+}
+
 func (s *server) Listen(port string) error {
 	return http.ListenAndServe(":"+port, s.mux)
 }
 
+// applies reloaded settings to the running sharding manager
+func (s *server) Reload(params model.ShardingManagerParams) error {
+	return s.shardingManager.UpdateParams(params)
+}
+
 func (s *server) livenessHandler(responseWriter http.ResponseWriter, request *http.Request) {
 	responseWriter.WriteHeader(200)
 	_, err := responseWriter.Write([]byte(fmt.Sprintln("OK")))
@@ -122,3 +219,187 @@ func (s *server) readinessHandler(responseWriter http.ResponseWriter, request *h
 		attribute.Key("code").String("200"),
 	))
 }
+
+// only lets requests through which carry the admin token as bearer token, the token file is read on every
+// request so that it can be rotated. Requests are refused when no token file is configured
+func (s *server) authorize(path string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		if s.adminTokenFile == "" {
+			s.writeError(responseWriter, path, http.StatusForbidden, fmt.Errorf("admin endpoints changing the assignment are disabled, set admin-token-file to enable them"))
+			return
+		}
+		data, err := os.ReadFile(s.adminTokenFile)
+		token := strings.TrimSpace(string(data))
+		if err != nil || token == "" {
+			log.Printf("failed to read admin token file %s: %v", s.adminTokenFile, err)
+			s.writeError(responseWriter, path, http.StatusInternalServerError, fmt.Errorf("admin token is not available"))
+			return
+		}
+		provided, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			responseWriter.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeError(responseWriter, path, http.StatusUnauthorized, fmt.Errorf("missing or invalid admin token"))
+			return
+		}
+		handler(responseWriter, request)
+	}
+}
+
+// returns placement overrides applied and rejected by the last sync
+func (s *server) overridesHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminOverridesPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	s.writeJSON(responseWriter, adminOverridesPath, s.shardingManager.GetOverrideStatus())
+}
+
+// returns health of every cluster shards are published to
+func (s *server) targetsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminTargetsPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	statuses := []model.ShardTargetStatus{}
+	if s.shardRouter != nil {
+		statuses = s.shardRouter.GetTargetStatus()
+	}
+	s.writeJSON(responseWriter, adminTargetsPath, statuses)
+}
+
+// returns the last assignment changes, the number of changes is set using the limit query parameter
+func (s *server) auditHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminAuditPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	limit := defaultAuditLimit
+	if value := request.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			s.writeError(responseWriter, adminAuditPath, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer, got %q", value))
+			return
+		}
+		limit = parsed
+	}
+	s.writeJSON(responseWriter, adminAuditPath, s.shardingManager.GetAuditEntries(limit))
+}
+
+// returns clusters currently handed off between operators
+func (s *server) migrationsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminMigrationsPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	s.writeJSON(responseWriter, adminMigrationsPath, s.shardingManager.GetMigrations())
+}
+
+// returns registry configuration rejected for removing too many clusters
+func (s *server) registryGuardHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminRegistryGuardPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	s.writeJSON(responseWriter, adminRegistryGuardPath, s.shardingManager.GetRegistryGuardStatus())
+}
+
+// accepts the rejected registry configuration, it is applied by the next sync
+func (s *server) registryGuardAcceptHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		s.writeError(responseWriter, adminRegistryGuardAcceptPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	change, err := s.shardingManager.AcceptRegistryChange()
+	if err != nil {
+		s.writeError(responseWriter, adminRegistryGuardAcceptPath, http.StatusConflict, err)
+		return
+	}
+	s.writeJSON(responseWriter, adminRegistryGuardAcceptPath, change)
+}
+
+// returns progress of the rollout of cluster moves along with moves queued for later waves
+func (s *server) rolloutHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminRolloutPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	s.writeJSON(responseWriter, adminRolloutPath, s.shardingManager.GetRolloutStatus())
+}
+
+// returns revisions of the assignment kept in the history and the revision rebalancing is frozen at
+func (s *server) revisionsHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminRevisionsPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	s.writeJSON(responseWriter, adminRevisionsPath, s.shardingManager.GetRevisions())
+}
+
+// rolls the assignment back to the requested revision and freezes rebalancing until it is resumed
+func (s *server) rollbackHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		s.writeError(responseWriter, adminRollbackPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	value := request.URL.Query().Get("revision")
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		s.writeError(responseWriter, adminRollbackPath, http.StatusBadRequest, fmt.Errorf("revision must be a positive integer, got %q", value))
+		return
+	}
+	rolledBack, err := s.shardingManager.Rollback(request.Context(), revision)
+	if err != nil {
+		s.writeError(responseWriter, adminRollbackPath, http.StatusConflict, err)
+		return
+	}
+	s.writeJSON(responseWriter, adminRollbackPath, rolledBack)
+}
+
+// resumes rebalancing frozen by a rollback
+func (s *server) resumeHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		s.writeError(responseWriter, adminResumePath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	err := s.shardingManager.Resume(request.Context())
+	if err != nil {
+		s.writeError(responseWriter, adminResumePath, http.StatusConflict, err)
+		return
+	}
+	s.writeJSON(responseWriter, adminResumePath, s.shardingManager.GetRevisions())
+}
+
+// returns shard changes the last sync would have made in dry run mode
+func (s *server) dryRunHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		s.writeError(responseWriter, adminDryRunPath, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", request.Method))
+		return
+	}
+	s.writeJSON(responseWriter, adminDryRunPath, s.shardingManager.GetDryRunStatus())
+}
+
+func (s *server) writeJSON(responseWriter http.ResponseWriter, path string, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		s.writeError(responseWriter, path, http.StatusInternalServerError, fmt.Errorf("failed to marshal response: %v", err))
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusOK)
+	_, err = responseWriter.Write(data)
+	if err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+	shardingManagerRequestsTotal.Increment(api.WithAttributes(
+		attribute.Key("path").String(path),
+		attribute.Key("code").String(strconv.Itoa(http.StatusOK)),
+	))
+}
+
+func (s *server) writeError(responseWriter http.ResponseWriter, path string, code int, err error) {
+	http.Error(responseWriter, err.Error(), code)
+	shardingManagerRequestsTotal.Increment(api.WithAttributes(
+		attribute.Key("path").String(path),
+		attribute.Key("code").String(strconv.Itoa(code)),
+	))
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		adminTokenFile string
+		authorization  string
+		expectedCode   int
+	}{
+		{
+			name: "Given no admin token file, " +
+				"When an admin endpoint changing the assignment is called, " +
+				"Then the request should be forbidden",
+			authorization: "Bearer secret",
+			expectedCode:  http.StatusForbidden,
+		},
+		{
+			name: "Given an admin token file, " +
+				"When an admin endpoint changing the assignment is called without token, " +
+				"Then the request should be unauthorized",
+			adminTokenFile: tokenFile,
+			expectedCode:   http.StatusUnauthorized,
+		},
+		{
+			name: "Given an admin token file, " +
+				"When an admin endpoint changing the assignment is called with another token, " +
+				"Then the request should be unauthorized",
+			adminTokenFile: tokenFile,
+			authorization:  "Bearer other",
+			expectedCode:   http.StatusUnauthorized,
+		},
+		{
+			name: "Given an admin token file which cannot be read, " +
+				"When an admin endpoint changing the assignment is called, " +
+				"Then the request should fail",
+			adminTokenFile: filepath.Join(t.TempDir(), "missing"),
+			authorization:  "Bearer secret",
+			expectedCode:   http.StatusInternalServerError,
+		},
+		{
+			name: "Given an admin token file, " +
+				"When an admin endpoint changing the assignment is called with the token, " +
+				"Then the request should be handled",
+			adminTokenFile: tokenFile,
+			authorization:  "Bearer secret",
+			expectedCode:   http.StatusOK,
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &server{adminTokenFile: c.adminTokenFile}
+			handler := s.authorize(adminRollbackPath, func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.WriteHeader(http.StatusOK)
+			})
+			request := httptest.NewRequest(http.MethodPost, adminRollbackPath+"?revision=1", nil)
+			if c.authorization != "" {
+				request.Header.Set("Authorization", c.authorization)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler(recorder, request)
+			if recorder.Code != c.expectedCode {
+				t.Errorf("expected status %d, got %d", c.expectedCode, recorder.Code)
+			}
+		})
+	}
+}